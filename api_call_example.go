@@ -7,7 +7,7 @@ import (
 	"github.com/parameterResolver/resolver"
 )
 
-func UsageForExtractParametersFromTextApi(service resolver.ISsmParameterService) {
+func UsageForExtractParametersFromTextApi(service resolver.IParameterProvider) {
 	fmt.Println("Example of ExtractParametersFromText API usage")
 
 	inputDoc := "Some text {{ ssm:/a/b/c/param1}}, some more text {{ssm-secure:param2}}"
@@ -20,12 +20,12 @@ func UsageForExtractParametersFromTextApi(service resolver.ISsmParameterService)
 	}
 
 	for ref, param := range resolvedParameters {
-		fmt.Printf("Parameter reference %s -> %s\n", ref, param)
+		fmt.Printf("Parameter reference %s -> %v\n", ref, param)
 	}
 	fmt.Println()
 }
 
-func UsageForResolveParameterReferenceList(service resolver.ISsmParameterService) {
+func UsageForResolveParameterReferenceList(service resolver.IParameterProvider) {
 	fmt.Println("Example of ResolveParameterReferenceList API usage")
 
 	parameterReferences := []string{
@@ -42,11 +42,11 @@ func UsageForResolveParameterReferenceList(service resolver.ISsmParameterService
 	}
 
 	for ref, param := range resolvedParameters {
-		fmt.Printf("Parameter reference %s -> %s\n\n", ref, param)
+		fmt.Printf("Parameter reference %s -> %v\n\n", ref, param)
 	}
 }
 
-func UsageForResolveParametersInText(service resolver.ISsmParameterService) {
+func UsageForResolveParametersInText(service resolver.IParameterProvider) {
 	fmt.Println("Example of ResolveParametersInText API usage")
 
 	unresolvedText := "Some text {{ ssm:/a/b/c/param1}}, some more text {{ssm-secure:param2}}"
@@ -62,7 +62,7 @@ func UsageForResolveParametersInText(service resolver.ISsmParameterService) {
 	fmt.Printf("Resolved doc:   %s\n\n", resolvedText)
 }
 
-func UsageForResolveParametersInFile(service resolver.ISsmParameterService) {
+func UsageForResolveParametersInFile(service resolver.IParameterProvider) {
 	fmt.Println("Example of ResolveParametersInFile API usage")
 
 	inputFilename := "./test-files/test.json"
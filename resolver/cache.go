@@ -0,0 +1,216 @@
+package resolver
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type CacheOptions struct {
+	// How long a resolved parameter is served from the cache. Zero (or negative) disables caching:
+	// every lookup goes to the provider and is counted as a miss.
+	TTL time.Duration
+	// TTL for secure parameters, TTL is used if not set
+	SecureTTL time.Duration
+	// Maximum number of entries kept for each of secure and non-secure parameters, unlimited if not set
+	MaxSize int
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Number of secure and non-secure entries currently cached
+	Size int
+}
+
+//
+// ParameterCache is an IParameterProvider that remembers parameters resolved by another provider,
+// so one instance can be shared by many resolve calls. Secure and non-secure parameters
+// are kept in separate stores. Missing parameters and errors are never cached.
+// It is safe for concurrent use.
+type ParameterCache struct {
+	provider IParameterProvider
+	options  CacheOptions
+
+	mutex     sync.Mutex
+	nonSecure *cacheStore
+	secure    *cacheStore
+	stats     CacheStats
+
+	// replaced in tests
+	now func() time.Time
+}
+
+var _ IPrefixedParameterProvider = (*ParameterCache)(nil)
+
+func NewParameterCache(provider IParameterProvider, options CacheOptions) *ParameterCache {
+	if options.SecureTTL == 0 {
+		options.SecureTTL = options.TTL
+	}
+
+	return &ParameterCache{
+		provider:  provider,
+		options:   options,
+		nonSecure: newCacheStore(options.TTL, options.MaxSize),
+		secure:    newCacheStore(options.SecureTTL, options.MaxSize),
+		now:       time.Now,
+	}
+}
+
+func (c *ParameterCache) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	result := map[string]SsmParameterInfo{}
+	referencesToFetch := []string{}
+
+	c.mutex.Lock()
+	now := c.now()
+	for _, ref := range parameterReferences {
+		if value, found := c.storeFor(ref, "").get(ref, now); found {
+			result[ref] = value
+			c.stats.Hits++
+		} else {
+			referencesToFetch = append(referencesToFetch, ref)
+			c.stats.Misses++
+		}
+	}
+	c.mutex.Unlock()
+
+	if len(referencesToFetch) == 0 {
+		return result, nil
+	}
+
+	fetched, err := c.provider.GetParameters(ctx, referencesToFetch)
+	var missingParametersError *MissingParametersError
+	if err != nil && !errors.As(err, &missingParametersError) {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	now = c.now()
+	for ref, value := range fetched {
+		c.stats.Evictions += uint64(c.storeFor(ref, value.Type).put(ref, value, now))
+		result[ref] = value
+	}
+	c.mutex.Unlock()
+
+	return result, err
+}
+
+//
+// Removes the given parameter references from the cache
+func (c *ParameterCache) Invalidate(parameterReferences ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, ref := range parameterReferences {
+		c.nonSecure.remove(ref)
+		c.secure.remove(ref)
+	}
+}
+
+//
+// Removes all entries from the cache
+func (c *ParameterCache) InvalidateAll() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.nonSecure = newCacheStore(c.options.TTL, c.options.MaxSize)
+	c.secure = newCacheStore(c.options.SecureTTL, c.options.MaxSize)
+}
+
+func (c *ParameterCache) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.stats
+	stats.Size = c.nonSecure.entries.Len() + c.secure.entries.Len()
+	return stats
+}
+
+func (c *ParameterCache) ReferencePrefixes() []ReferencePrefix {
+	return referencePrefixesOf(c.provider)
+}
+
+func (c *ParameterCache) storeFor(parameterReference string, parameterType string) *cacheStore {
+	if prefix, _ := findReferencePrefix(c.ReferencePrefixes(), parameterReference); prefix.Secure || parameterType == secureStringType {
+		return c.secure
+	}
+	if _, found := c.secure.index[parameterReference]; found {
+		return c.secure
+	}
+	return c.nonSecure
+}
+
+//
+// LRU list of cached parameters with expiration
+type cacheStore struct {
+	ttl     time.Duration
+	maxSize int
+	// most recently used entries are at the front
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+type cacheEntry struct {
+	reference string
+	value     SsmParameterInfo
+	expiresAt time.Time
+}
+
+func newCacheStore(ttl time.Duration, maxSize int) *cacheStore {
+	return &cacheStore{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: list.New(),
+		index:   map[string]*list.Element{},
+	}
+}
+
+func (s *cacheStore) get(reference string, now time.Time) (SsmParameterInfo, bool) {
+	element, found := s.index[reference]
+	if !found {
+		return SsmParameterInfo{}, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if !now.Before(entry.expiresAt) {
+		s.remove(reference)
+		return SsmParameterInfo{}, false
+	}
+
+	s.entries.MoveToFront(element)
+	return entry.value, true
+}
+
+//
+// Stores the value and returns the number of entries evicted to stay within maxSize
+func (s *cacheStore) put(reference string, value SsmParameterInfo, now time.Time) int {
+	// the entry would be expired already
+	if s.ttl <= 0 {
+		return 0
+	}
+
+	if element, found := s.index[reference]; found {
+		element.Value = &cacheEntry{reference: reference, value: value, expiresAt: now.Add(s.ttl)}
+		s.entries.MoveToFront(element)
+		return 0
+	}
+
+	s.index[reference] = s.entries.PushFront(&cacheEntry{reference: reference, value: value, expiresAt: now.Add(s.ttl)})
+
+	evicted := 0
+	for s.maxSize > 0 && s.entries.Len() > s.maxSize {
+		s.remove(s.entries.Back().Value.(*cacheEntry).reference)
+		evicted++
+	}
+	return evicted
+}
+
+func (s *cacheStore) remove(reference string) {
+	if element, found := s.index[reference]; found {
+		s.entries.Remove(element)
+		delete(s.index, reference)
+	}
+}
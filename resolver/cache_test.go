@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//
+// Provider that counts how many times each reference was requested
+type countingProviderMock struct {
+	ServiceMockedObjectWithRecords
+	mutex    sync.Mutex
+	requests map[string]int
+}
+
+func newCountingProviderMock(records map[string]SsmParameterInfo) *countingProviderMock {
+	return &countingProviderMock{
+		ServiceMockedObjectWithRecords: NewServiceMockedObjectWithExtraRecords(records),
+		requests:                       map[string]int{},
+	}
+}
+
+func (m *countingProviderMock) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	m.mutex.Lock()
+	for _, ref := range parameterReferences {
+		m.requests[ref]++
+	}
+	m.mutex.Unlock()
+
+	return m.ServiceMockedObjectWithRecords.GetParameters(ctx, parameterReferences)
+}
+
+var cacheTestRecords = map[string]SsmParameterInfo{
+	"ssm:param1":        {Name: "param1", Type: stringType, Value: "value_param1"},
+	"ssm:param2":        {Name: "param2", Type: stringType, Value: "value_param2"},
+	"ssm-secure:param3": {Name: "param3", Type: secureStringType, Value: "value_param3"},
+}
+
+func TestParameterCacheServesRepeatedReferences(t *testing.T) {
+	provider := newCountingProviderMock(cacheTestRecords)
+	cache := NewParameterCache(provider, CacheOptions{TTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		output, err := ResolveParametersInText(cache, "{{ssm:param1}} {{ssm-secure:param3}}", ResolveOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "value_param1 value_param3", output)
+	}
+
+	assert.Equal(t, 1, provider.requests["ssm:param1"])
+	assert.Equal(t, 1, provider.requests["ssm-secure:param3"])
+	assert.Equal(t, CacheStats{Hits: 4, Misses: 2, Size: 2}, cache.Stats())
+}
+
+func TestParameterCacheWithoutTTLDoesNotCache(t *testing.T) {
+	provider := newCountingProviderMock(cacheTestRecords)
+	cache := NewParameterCache(provider, CacheOptions{})
+
+	for i := 0; i < 2; i++ {
+		output, err := ResolveParametersInText(cache, "{{ssm:param1}}", ResolveOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, "value_param1", output)
+	}
+
+	assert.Equal(t, 2, provider.requests["ssm:param1"])
+	assert.Equal(t, CacheStats{Misses: 2}, cache.Stats())
+}
+
+func TestParameterCacheExpiresEntries(t *testing.T) {
+	provider := newCountingProviderMock(cacheTestRecords)
+	cache := NewParameterCache(provider, CacheOptions{TTL: time.Minute, SecureTTL: time.Second})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.GetParameters(context.Background(), []string{"ssm:param1", "ssm-secure:param3"})
+	assert.Nil(t, err)
+
+	now = now.Add(2 * time.Second)
+	_, err = cache.GetParameters(context.Background(), []string{"ssm:param1", "ssm-secure:param3"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, provider.requests["ssm:param1"])
+	assert.Equal(t, 2, provider.requests["ssm-secure:param3"])
+}
+
+func TestParameterCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	records := map[string]SsmParameterInfo{}
+	for i := 0; i < 5; i++ {
+		ref := ssmNonSecurePrefix + "param" + strconv.Itoa(i)
+		records[ref] = SsmParameterInfo{Name: ref, Type: stringType, Value: "value"}
+	}
+	provider := newCountingProviderMock(records)
+	cache := NewParameterCache(provider, CacheOptions{TTL: time.Minute, MaxSize: 2})
+
+	for _, ref := range []string{"ssm:param0", "ssm:param1", "ssm:param0", "ssm:param2", "ssm:param0", "ssm:param1"} {
+		_, err := cache.GetParameters(context.Background(), []string{ref})
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, 1, provider.requests["ssm:param0"])
+	assert.Equal(t, 2, provider.requests["ssm:param1"])
+	assert.Equal(t, uint64(2), cache.Stats().Evictions)
+	assert.Equal(t, 2, cache.Stats().Size)
+}
+
+func TestParameterCacheInvalidate(t *testing.T) {
+	provider := newCountingProviderMock(cacheTestRecords)
+	cache := NewParameterCache(provider, CacheOptions{TTL: time.Minute})
+	references := []string{"ssm:param1", "ssm:param2", "ssm-secure:param3"}
+
+	_, _ = cache.GetParameters(context.Background(), references)
+	cache.Invalidate("ssm:param1", "ssm-secure:param3")
+	_, _ = cache.GetParameters(context.Background(), references)
+	cache.InvalidateAll()
+	_, _ = cache.GetParameters(context.Background(), references)
+
+	assert.Equal(t, 3, provider.requests["ssm:param1"])
+	assert.Equal(t, 2, provider.requests["ssm:param2"])
+	assert.Equal(t, 3, provider.requests["ssm-secure:param3"])
+}
+
+func TestParameterCacheDoesNotCacheMissingParameters(t *testing.T) {
+	provider := newCountingProviderMock(cacheTestRecords)
+	cache := NewParameterCache(provider, CacheOptions{TTL: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		result, err := cache.GetParameters(context.Background(), []string{"ssm:param1", "ssm:missing"})
+
+		var missingParametersError *MissingParametersError
+		assert.True(t, errors.As(err, &missingParametersError))
+		assert.Equal(t, []string{"ssm:missing"}, missingParametersError.References)
+		assert.Equal(t, "value_param1", result["ssm:param1"].Value)
+	}
+
+	assert.Equal(t, 1, provider.requests["ssm:param1"])
+	assert.Equal(t, 2, provider.requests["ssm:missing"])
+}
+
+func TestParameterCacheConcurrentUse(t *testing.T) {
+	provider := newCountingProviderMock(cacheTestRecords)
+	cache := NewParameterCache(provider, CacheOptions{TTL: time.Minute, MaxSize: 1})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := cache.GetParameters(context.Background(), []string{"ssm:param1", "ssm:param2", "ssm-secure:param3"})
+			assert.Nil(t, err)
+			assert.Equal(t, 3, len(result))
+			cache.Invalidate("ssm:param2")
+		}()
+	}
+	wg.Wait()
+}
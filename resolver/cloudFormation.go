@@ -0,0 +1,46 @@
+package resolver
+
+import "strings"
+
+//
+// CloudFormation dynamic references look like {{resolve:service:reference}},
+// see https://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/dynamic-references.html
+const cloudFormationResolvePrefix = "resolve:"
+
+//
+// Secrets Manager dynamic references select the secret value with this literal
+const cloudFormationSecretString = "SecretString"
+
+func isCloudFormationPrefix(prefix string) bool {
+	return prefix == ssmNonSecurePrefix || prefix == ssmSecurePrefix || prefix == secretsManagerPrefix
+}
+
+//
+// Translates the part of a dynamic reference after resolve: into a reference. The ssm and ssm-secure forms
+// (ssm:name:version) are already references. The Secrets Manager form
+// secretsmanager:secret-id:SecretString:json-key:version-stage:version-id
+// becomes secretsmanager:secret-id:json-key:version-stage:version-id.
+func referenceFromCloudFormation(dynamicReference string) (string, string) {
+	if !strings.HasPrefix(dynamicReference, secretsManagerPrefix) {
+		return dynamicReference, ""
+	}
+
+	body := strings.TrimPrefix(dynamicReference, secretsManagerPrefix)
+
+	idFields := 1
+	if strings.HasPrefix(body, arnPrefix) {
+		idFields = secretArnFields
+	}
+	fields := strings.Split(body, ":")
+	if len(fields) <= idFields {
+		return dynamicReference, ""
+	}
+
+	if fields[idFields] != cloudFormationSecretString {
+		return "", "secret value must be selected with " + cloudFormationSecretString
+	}
+
+	// json-key, version-stage and version-id are all optional, trailing empty fields are dropped
+	reference := secretsManagerPrefix + strings.Join(append(fields[:idFields:idFields], fields[idFields+1:]...), ":")
+	return strings.TrimRight(reference, ":"), ""
+}
@@ -0,0 +1,71 @@
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferenceFromCloudFormation(t *testing.T) {
+	testCases := map[string]string{
+		"ssm:/a/b":                            "ssm:/a/b",
+		"ssm:/a/b:3":                          "ssm:/a/b:3",
+		"ssm-secure:param:1":                  "ssm-secure:param:1",
+		"secretsmanager:db":                   "secretsmanager:db",
+		"secretsmanager:db:SecretString":      "secretsmanager:db",
+		"secretsmanager:db:SecretString:pass": "secretsmanager:db:pass",
+		"secretsmanager:db:SecretString:pass:AWSPREVIOUS":                           "secretsmanager:db:pass:AWSPREVIOUS",
+		"secretsmanager:db:SecretString::AWSPREVIOUS":                               "secretsmanager:db::AWSPREVIOUS",
+		"secretsmanager:db:SecretString:pass::b1f6d2c4-6a3e-4b5c-9d7e-0123456789ab": "secretsmanager:db:pass::b1f6d2c4-6a3e-4b5c-9d7e-0123456789ab",
+		"secretsmanager:" + testSecretArn + ":SecretString:pass":                    "secretsmanager:" + testSecretArn + ":pass",
+	}
+	for dynamicReference, expected := range testCases {
+		ref, reason := referenceFromCloudFormation(dynamicReference)
+		assert.Equal(t, "", reason, dynamicReference)
+		assert.Equal(t, expected, ref, dynamicReference)
+	}
+
+	_, reason := referenceFromCloudFormation("secretsmanager:db:SecretBinary")
+	assert.NotEqual(t, "", reason)
+}
+
+func TestResolveParametersInTextWithCloudFormationSyntax(t *testing.T) {
+	ssmService := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/db/host":    {Name: "/app/db/host", Type: stringType, Value: "db.example.com"},
+		"ssm:/app/db/port:2":  {Name: "/app/db/port", Type: stringType, Value: "5432", Version: 2},
+		"ssm-secure:/app/key": {Name: "/app/key", Type: secureStringType, Value: "secret-key"},
+	})
+	secretsClient := newSecretsManagerClientMock()
+	secretsClient.secrets["db::0123456789abcdef0123456789abcdef"] = &secretsmanager.GetSecretValueOutput{
+		Name: aws.String("db"), SecretString: aws.String(`{"password":"pinned"}`)}
+	router := NewProviderRouter().
+		Register(&ssmService).
+		Register(&SecretsManagerProvider{SecretsManagerClient: secretsClient})
+
+	text := "{{resolve:ssm:/app/db/host}}:{{ resolve:ssm:/app/db/port:2 }} key={{resolve:ssm-secure:/app/key}} " +
+		"user={{resolve:secretsmanager:db:SecretString:username}} old={{resolve:secretsmanager:db:SecretString:password:AWSPREVIOUS}} " +
+		"pinned={{resolve:secretsmanager:db:SecretString:password::0123456789abcdef0123456789abcdef}} native={{ssm:/app/db/host}}"
+
+	output, err := ResolveParametersInText(router, text, ResolveOptions{CloudFormationSyntax: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "db.example.com:5432 key=secret-key user=admin old=old pinned=pinned native=db.example.com", output)
+
+	output, err = ResolveParametersInText(router, text, ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, output, "{{resolve:ssm:/app/db/host}}")
+	assert.Contains(t, output, "native=db.example.com")
+}
+
+func TestExtractParametersFromTextWithMalformedCloudFormationReference(t *testing.T) {
+	router := NewProviderRouter().Register(&SecretsManagerProvider{SecretsManagerClient: newSecretsManagerClientMock()})
+
+	_, err := ExtractParametersFromText(router, "{{resolve:secretsmanager:db:SecretBinary}}", ResolveOptions{CloudFormationSyntax: true})
+
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+	assert.Equal(t, "resolve:secretsmanager:db:SecretBinary", invalidReferenceError.References[0].Reference)
+}
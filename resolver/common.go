@@ -1,24 +1,45 @@
 package resolver
 
-import "regexp"
-
 const ssmNonSecurePrefix = "ssm:"
 const ssmSecurePrefix = "ssm-secure:"
+const ssmPathPrefix = "ssm-path:"
+const ssmListPrefix = "ssm-list:"
+const secretsManagerPrefix = "secretsmanager:"
+const vaultPrefix = "vault:"
 
 const secureStringType = "SecureString"
 const stringType = "String"
-
-//
-// SSM Parameter placeholder - relaxed regular expression
-var parameterPlaceholder = regexp.MustCompile("{{\\s*(" + ssmNonSecurePrefix + "[\\w-/]+)\\s*}}")
-var secureParameterPlaceholder = regexp.MustCompile("{{\\s*(" + ssmSecurePrefix + "[\\w-/]+)\\s*}}")
+const stringListType = "StringList"
+const secretStringType = "SecretString"
+const secretBinaryType = "SecretBinary"
+const vaultSecretType = "VaultSecret"
 
 type ResolveOptions struct {
 	IgnoreSecureParameters bool
+	// Maximum number of batch requests sent to the parameter store at the same time, 1 if not set
+	MaxConcurrentRequests int
+	// Also recognize CloudFormation dynamic references like {{resolve:ssm:name:1}},
+	// {{resolve:ssm-secure:name:1}} and {{resolve:secretsmanager:secret-id:SecretString:json-key}}
+	CloudFormationSyntax bool
+	// Accept defaults ({{ssm-secure:name || "value"}}) and the optional marker ({{?ssm-secure:name}})
+	// on secure references, where they are rejected otherwise
+	AllowSecureDefaults bool
+	// Escape substituted values for the format of the document and check that the resolved
+	// document is well-formed. Placeholders with filters are not escaped.
+	DocumentFormat DocumentFormat
+	// Used by ResolveParametersInJSON and ResolveParametersInYAML: also resolve placeholders in keys
+	ResolveKeys bool
+	// Used by ResolveParametersInJSON and ResolveParametersInYAML: a string that is exactly one placeholder
+	// becomes a number or boolean when the value reads as one, and a list for StringList parameters
+	TypedValues bool
 }
 
 type SsmParameterInfo struct {
-	Name  string
-	Type  string
-	Value string
+	Name    string
+	Type    string
+	Value   string
+	Version int64
+	ARN     string
+	// Elements of a StringList parameter, nil for other types
+	Elements []string
 }
@@ -0,0 +1,743 @@
+package resolver
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+//
+// DocumentFormat selects how substituted values are escaped. With a format set, every value
+// is escaped for the place it appears in, e.g. inside a JSON string or an XML attribute,
+// and the resolved document is checked to be well-formed.
+type DocumentFormat string
+
+const (
+	// Values are substituted as they are
+	DocumentFormatNone DocumentFormat = ""
+	// The format is taken from the file extension, or from the content for JSON and XML;
+	// documents of unknown format are resolved without escaping
+	DocumentFormatAuto       DocumentFormat = "auto"
+	DocumentFormatJSON       DocumentFormat = "json"
+	DocumentFormatYAML       DocumentFormat = "yaml"
+	DocumentFormatTOML       DocumentFormat = "toml"
+	DocumentFormatProperties DocumentFormat = "properties"
+	DocumentFormatXML        DocumentFormat = "xml"
+)
+
+var documentFormatsByExtension = map[string]DocumentFormat{
+	".json":       DocumentFormatJSON,
+	".yaml":       DocumentFormatYAML,
+	".yml":        DocumentFormatYAML,
+	".toml":       DocumentFormatTOML,
+	".properties": DocumentFormatProperties,
+	".xml":        DocumentFormatXML,
+}
+
+//
+// Returned when a document resolved with a DocumentFormat is not well-formed afterwards
+type MalformedDocumentError struct {
+	Format DocumentFormat
+	// 1-based line of the problem, 0 when the parser doesn't tell
+	Line int
+	Err  error
+}
+
+func (e *MalformedDocumentError) Error() string {
+	message := "resolved document is not well-formed " + string(e.Format)
+	if e.Line > 0 {
+		message += " at line " + strconv.Itoa(e.Line)
+	}
+	return message + ": " + e.Err.Error()
+}
+
+func (e *MalformedDocumentError) Unwrap() error {
+	return e.Err
+}
+
+//
+// Returns the format to escape a document with, resolving DocumentFormatAuto by the extension
+// of the first file name that has a known one, then by the content
+func detectDocumentFormat(format DocumentFormat, text string, fileNames ...string) DocumentFormat {
+	if format != DocumentFormatAuto {
+		return format
+	}
+
+	for _, fileName := range fileNames {
+		if detected, found := documentFormatsByExtension[strings.ToLower(filepath.Ext(fileName))]; found {
+			return detected
+		}
+	}
+
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(trimmed, "{{"):
+		// starts with a placeholder, not a JSON object
+	case strings.HasPrefix(trimmed, "{"), strings.HasPrefix(trimmed, "["):
+		return DocumentFormatJSON
+	case strings.HasPrefix(trimmed, "<"):
+		return DocumentFormatXML
+	}
+	return DocumentFormatNone
+}
+
+//
+// Kind of place in a document a placeholder appears in
+type valueContextKind int
+
+const (
+	// Substituted as is: outside of strings, in comments, or in documents without format
+	rawContext valueContextKind = iota
+	jsonStringContext
+	yamlPlainContext
+	yamlDoubleQuotedContext
+	yamlSingleQuotedContext
+	yamlBlockContext
+	tomlBasicStringContext
+	tomlMultilineBasicStringContext
+	tomlLiteralStringContext
+	tomlMultilineLiteralStringContext
+	propertiesKeyContext
+	// at the beginning of a value, where white space has to be escaped
+	propertiesValueStartContext
+	propertiesValueContext
+	xmlTextContext
+	xmlAttributeContext
+	xmlCDATAContext
+	xmlCommentContext
+)
+
+type valueContext struct {
+	kind valueContextKind
+	// indentation of the lines of a YAML block scalar
+	indent string
+	// whether a placeholder in a YAML plain scalar begins or ends the scalar, and if the scalar is inside a flow collection
+	startsScalar bool
+	endsScalar   bool
+	inFlow       bool
+}
+
+//
+// Returns the context of every placeholder, given by the spans (start and end offsets) of the placeholders
+// in text. The placeholders themselves are skipped when the document is scanned.
+func documentContexts(format DocumentFormat, text string, spans [][]int) []valueContext {
+	lexers := map[DocumentFormat]func(*contextLexer){
+		DocumentFormatJSON:       lexJSON,
+		DocumentFormatYAML:       lexYAML,
+		DocumentFormatTOML:       lexTOML,
+		DocumentFormatProperties: lexProperties,
+		DocumentFormatXML:        lexXML,
+	}
+
+	lexer := &contextLexer{text: text, spans: spans, contexts: make([]valueContext, len(spans))}
+	if lex, found := lexers[format]; found {
+		lex(lexer)
+	}
+	return lexer.contexts
+}
+
+//
+// Walks a document byte by byte, stepping over placeholders and recording the current context for each of them
+type contextLexer struct {
+	text     string
+	spans    [][]int
+	contexts []valueContext
+	// next placeholder to be reached
+	nextSpan int
+	position int
+	current  valueContext
+}
+
+//
+// Records the current context for a placeholder at the current position and steps over it. Returns false
+// when there is no placeholder there.
+func (l *contextLexer) skipPlaceholder() bool {
+	if l.nextSpan >= len(l.spans) || l.position != l.spans[l.nextSpan][0] {
+		return false
+	}
+	l.contexts[l.nextSpan] = l.current
+	l.position = l.spans[l.nextSpan][1]
+	l.nextSpan++
+	return true
+}
+
+func (l *contextLexer) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(l.text[l.position:], prefix)
+}
+
+func lexJSON(l *contextLexer) {
+	escaped := false
+	for l.position < len(l.text) {
+		if l.skipPlaceholder() {
+			escaped = false
+			continue
+		}
+
+		c := l.text[l.position]
+		switch {
+		case l.current.kind == jsonStringContext && escaped:
+			escaped = false
+		case l.current.kind == jsonStringContext && c == '\\':
+			escaped = true
+		case l.current.kind == jsonStringContext && c == '"':
+			l.current.kind = rawContext
+		case l.current.kind == rawContext && c == '"':
+			l.current.kind = jsonStringContext
+		}
+		l.position++
+	}
+}
+
+//
+// YAML is followed far enough to tell quoted scalars, comments and block scalars from plain values
+func lexYAML(l *contextLexer) {
+	escaped := false
+	// last non-blank character on the line outside of placeholders, 0 at line start
+	previous := byte(0)
+	lineIndent := ""
+	// indentation of the line that started a block scalar, nil outside of block scalars
+	var blockParentIndent *string
+	// nesting of flow collections, [ and {
+	flowLevel := 0
+
+	for l.position < len(l.text) {
+		atLineStart := l.position == 0 || l.text[l.position-1] == '\n'
+		if atLineStart {
+			lineEnd := strings.IndexByte(l.text[l.position:], '\n')
+			if lineEnd < 0 {
+				lineEnd = len(l.text) - l.position
+			}
+			line := l.text[l.position : l.position+lineEnd]
+			lineIndent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+
+			if blockParentIndent != nil && strings.TrimSpace(line) != "" && len(lineIndent) <= len(*blockParentIndent) {
+				blockParentIndent = nil
+				l.current = valueContext{kind: yamlPlainContext}
+			}
+			if blockParentIndent != nil {
+				l.current = valueContext{kind: yamlBlockContext, indent: lineIndent}
+			} else if l.current.kind == rawContext || l.current.kind == yamlPlainContext {
+				l.current.kind = yamlPlainContext
+			}
+			previous = 0
+		}
+
+		if l.current.kind == yamlPlainContext && l.nextSpan < len(l.spans) && l.position == l.spans[l.nextSpan][0] {
+			l.current.startsScalar = startsYAMLScalar(l.text, l.position, previous)
+			l.current.endsScalar = endsYAMLScalar(l.text[l.spans[l.nextSpan][1]:], flowLevel > 0)
+			l.current.inFlow = flowLevel > 0
+		}
+		if l.skipPlaceholder() {
+			escaped = false
+			// the placeholder is part of a plain value, quotes after it don't start a scalar
+			if l.current.kind == yamlPlainContext {
+				previous = 'x'
+			}
+			l.current.startsScalar, l.current.endsScalar, l.current.inFlow = false, false, false
+			continue
+		}
+
+		c := l.text[l.position]
+		switch l.current.kind {
+		case yamlDoubleQuotedContext:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				l.current.kind = yamlPlainContext
+				previous = c
+			}
+
+		case yamlSingleQuotedContext:
+			if c == '\'' {
+				if l.hasPrefix("''") {
+					l.position++
+				} else {
+					l.current.kind = yamlPlainContext
+					previous = c
+				}
+			}
+
+		case rawContext:
+			// comment
+			if c == '\n' {
+				l.current.kind = yamlPlainContext
+			}
+
+		case yamlPlainContext:
+			blankBefore := l.position == 0 || strings.IndexByte(" \t\n", l.text[l.position-1]) >= 0
+			startsScalar := startsYAMLScalar(l.text, l.position, previous)
+
+			switch {
+			case c == '#' && blankBefore:
+				l.current.kind = rawContext
+			case c == '"' && startsScalar:
+				l.current.kind = yamlDoubleQuotedContext
+			case c == '\'' && startsScalar:
+				l.current.kind = yamlSingleQuotedContext
+			case (c == '|' || c == '>') && startsScalar && previous != 0 && isYAMLBlockHeader(l.text[l.position+1:]):
+				indent := lineIndent
+				blockParentIndent = &indent
+				l.position += strings.IndexByte(l.text[l.position:]+"\n", '\n')
+				continue
+			case c != ' ' && c != '\t' && c != '\n':
+				if (c == '[' || c == '{') && startsScalar {
+					flowLevel++
+				} else if (c == ']' || c == '}') && flowLevel > 0 {
+					flowLevel--
+				}
+				previous = c
+			}
+		}
+		l.position++
+	}
+}
+
+//
+// Returns whether a plain scalar starts at position, given the last non-blank character before it on the line
+func startsYAMLScalar(text string, position int, previous byte) bool {
+	blankBefore := position == 0 || strings.IndexByte(" \t\n", text[position-1]) >= 0
+	return previous == 0 || (strings.IndexByte(":-?", previous) >= 0 && blankBefore) || strings.IndexByte("[{,", previous) >= 0
+}
+
+//
+// Returns whether the plain scalar ends before rest: at the end of the line, a comment, a mapping value
+// indicator or, in flow collections, a flow indicator
+func endsYAMLScalar(rest string, inFlow bool) bool {
+	trimmed := strings.TrimLeft(rest, " \t")
+	switch {
+	case trimmed == "" || trimmed[0] == '\n' || trimmed[0] == '\r':
+		return true
+	case trimmed[0] == '#':
+		return len(trimmed) < len(rest)
+	case trimmed[0] == ':':
+		return len(trimmed) == 1 || strings.IndexByte(" \t\r\n", trimmed[1]) >= 0 || inFlow
+	case inFlow:
+		return strings.IndexByte(",]}", trimmed[0]) >= 0
+	}
+	return false
+}
+
+var yamlBlockHeaderRest = regexp.MustCompile(`^[-+0-9]*[ \t]*(#.*)?(\n|$)`)
+
+func isYAMLBlockHeader(rest string) bool {
+	return yamlBlockHeaderRest.MatchString(rest)
+}
+
+func lexTOML(l *contextLexer) {
+	escaped := false
+	comment := false
+	for l.position < len(l.text) {
+		if l.skipPlaceholder() {
+			escaped = false
+			continue
+		}
+
+		c := l.text[l.position]
+		switch l.current.kind {
+		case rawContext:
+			switch {
+			case comment:
+				comment = c != '\n'
+			case c == '#':
+				comment = true
+			case l.hasPrefix(`"""`):
+				l.current.kind = tomlMultilineBasicStringContext
+				l.position += 2
+			case l.hasPrefix(`'''`):
+				l.current.kind = tomlMultilineLiteralStringContext
+				l.position += 2
+			case c == '"':
+				l.current.kind = tomlBasicStringContext
+			case c == '\'':
+				l.current.kind = tomlLiteralStringContext
+			}
+
+		case tomlBasicStringContext, tomlMultilineBasicStringContext:
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if l.current.kind == tomlMultilineBasicStringContext && l.hasPrefix(`"""`) {
+				l.current.kind = rawContext
+				l.position += 2
+			} else if l.current.kind == tomlBasicStringContext && c == '"' {
+				l.current.kind = rawContext
+			}
+
+		case tomlLiteralStringContext:
+			if c == '\'' {
+				l.current.kind = rawContext
+			}
+
+		case tomlMultilineLiteralStringContext:
+			if l.hasPrefix(`'''`) {
+				l.current.kind = rawContext
+				l.position += 2
+			}
+		}
+		l.position++
+	}
+}
+
+func lexProperties(l *contextLexer) {
+	comment := false
+	continued := false
+	// the key and value are separated by white space and at most one = or :
+	separatorSeen := false
+	for l.position < len(l.text) {
+		atLineStart := l.position == 0 || l.text[l.position-1] == '\n'
+		if atLineStart {
+			line := strings.TrimLeft(l.text[l.position:], " \t\f")
+			comment = !continued && (strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!"))
+			switch {
+			case comment:
+				l.current.kind = rawContext
+			case !continued:
+				l.current.kind = propertiesKeyContext
+			}
+			continued = false
+		}
+
+		if l.skipPlaceholder() {
+			if l.current.kind == propertiesValueStartContext {
+				l.current.kind = propertiesValueContext
+			}
+			continue
+		}
+
+		c := l.text[l.position]
+		if !comment {
+			blank := c == ' ' || c == '\t' || c == '\f'
+			switch {
+			case c == '\\':
+				if l.position+1 < len(l.text) && l.text[l.position+1] == '\n' {
+					continued = true
+				} else if l.current.kind == propertiesValueStartContext {
+					l.current.kind = propertiesValueContext
+				}
+				l.position++
+			case l.current.kind == propertiesKeyContext && (c == '=' || c == ':' || blank):
+				l.current.kind = propertiesValueStartContext
+				separatorSeen = c == '=' || c == ':'
+			case l.current.kind == propertiesValueStartContext && (c == '=' || c == ':') && !separatorSeen:
+				separatorSeen = true
+			case l.current.kind == propertiesValueStartContext && !blank:
+				l.current.kind = propertiesValueContext
+			}
+		}
+		l.position++
+	}
+}
+
+func lexXML(l *contextLexer) {
+	// quote character of the current attribute value
+	var quote byte
+	l.current.kind = xmlTextContext
+
+	for l.position < len(l.text) {
+		if l.skipPlaceholder() {
+			continue
+		}
+
+		c := l.text[l.position]
+		switch l.current.kind {
+		case xmlTextContext:
+			switch {
+			case l.hasPrefix("<![CDATA["):
+				l.current.kind = xmlCDATAContext
+				l.position += len("<![CDATA[") - 1
+			case l.hasPrefix("<!--"):
+				l.current.kind = xmlCommentContext
+				l.position += len("<!--") - 1
+			case c == '<':
+				l.current.kind = rawContext
+			}
+
+		case xmlCDATAContext:
+			if l.hasPrefix("]]>") {
+				l.current.kind = xmlTextContext
+				l.position += 2
+			}
+
+		case xmlCommentContext:
+			if l.hasPrefix("-->") {
+				l.current.kind = xmlTextContext
+				l.position += 2
+			}
+
+		case xmlAttributeContext:
+			if c == quote {
+				l.current.kind = rawContext
+			}
+
+		case rawContext:
+			// inside a tag
+			switch c {
+			case '"', '\'':
+				quote = c
+				l.current.kind = xmlAttributeContext
+			case '>':
+				l.current.kind = xmlTextContext
+			}
+		}
+		l.position++
+	}
+}
+
+var xmlAttributeWhiteSpaceReplacer = strings.NewReplacer("\n", "&#10;", "\r", "&#13;", "\t", "&#9;")
+
+//
+// Values that can stay plain scalars: no indicator as first character, no flow indicators
+// (, [ ] { }) and no : or # that could start a mapping value or a comment
+var yamlPlainSafeValue = regexp.MustCompile(`^[a-zA-Z0-9_./+=(;$^][a-zA-Z0-9_./@+=~();$%^*\- ]*$`)
+
+//
+// Scalars YAML 1.1 parsers read as booleans, yaml.v3 only resolves true and false
+var yaml11Booleans = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true, "n": true, "N": true, "no": true, "No": true, "NO": true,
+	"on": true, "On": true, "ON": true, "off": true, "Off": true, "OFF": true,
+}
+
+//
+// Returns whether value can be written as a plain YAML scalar and is still read as the same string
+func isYAMLPlainString(value string) bool {
+	if !yamlPlainSafeValue.MatchString(value) || strings.HasSuffix(value, " ") || yaml11Booleans[value] {
+		return false
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &document); err != nil || len(document.Content) != 1 {
+		return false
+	}
+	scalar := document.Content[0]
+	return scalar.Kind == yaml.ScalarNode && scalar.ShortTag() == "!!str" && scalar.Value == value
+}
+
+//
+// Checks a value substituted into part of a plain YAML scalar, e.g. http://host:{{ssm:/port}}/x.
+// Quotes can't be used there, so values that would end the scalar or change its meaning are rejected.
+func escapeYAMLPlainPart(context valueContext, value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	unsafe := strings.ContainsAny(value, "\n\r") || strings.Contains(value, ": ") || strings.Contains(value, " #") ||
+		strings.HasSuffix(value, ":") || strings.HasPrefix(value, "#") ||
+		(context.inFlow && strings.ContainsAny(value, ",[]{}"))
+	if context.startsScalar {
+		first := value[0]
+		unsafe = unsafe || strings.IndexByte(" \t#&*!|>'\"%@`,[]{}", first) >= 0 ||
+			(strings.IndexByte("-?:", first) >= 0 && (len(value) == 1 || value[1] == ' ' || value[1] == '\t'))
+	}
+	if context.endsScalar {
+		unsafe = unsafe || strings.HasSuffix(value, " ") || strings.HasSuffix(value, "\t")
+	}
+
+	if unsafe {
+		return "", errors.New("value can't be written into part of a YAML plain scalar, it would change the document")
+	}
+	return value, nil
+}
+
+//
+// Escapes value for the context it is substituted into
+func escapeForContext(context valueContext, value string) (string, error) {
+	switch context.kind {
+	case jsonStringContext, yamlDoubleQuotedContext, tomlBasicStringContext, tomlMultilineBasicStringContext:
+		return jsonStringFilter(value)
+
+	case yamlPlainContext:
+		if !context.startsScalar || !context.endsScalar {
+			return escapeYAMLPlainPart(context, value)
+		}
+		// values that would read as another type, like null or 5432, are quoted to stay strings
+		if isYAMLPlainString(value) {
+			return value, nil
+		}
+		return jsonString(value)
+
+	case yamlSingleQuotedContext:
+		// line breaks in single-quoted scalars are folded, and there is no escape for them
+		if strings.ContainsAny(value, "\n\r") {
+			return "", errors.New("value can't be written into a YAML single-quoted scalar, it contains a line break")
+		}
+		return strings.ReplaceAll(value, "'", "''"), nil
+
+	case yamlBlockContext:
+		return strings.ReplaceAll(value, "\n", "\n"+context.indent), nil
+
+	case tomlLiteralStringContext:
+		if strings.ContainsAny(value, "'\n\r") {
+			return "", errors.New("value can't be written into a TOML literal string, it contains ' or a line break")
+		}
+		return value, nil
+
+	case tomlMultilineLiteralStringContext:
+		if strings.Contains(value, "'''") {
+			return "", errors.New("value can't be written into a TOML multi-line literal string, it contains '''")
+		}
+		return value, nil
+
+	case propertiesKeyContext:
+		return escapeProperty(value, true, true), nil
+
+	case propertiesValueStartContext:
+		return escapeProperty(value, false, true), nil
+
+	case propertiesValueContext:
+		return escapeProperty(value, false, false), nil
+
+	case xmlTextContext:
+		return xmlFilter(value)
+
+	case xmlAttributeContext:
+		// parsers normalize white space in attribute values unless it is escaped
+		escaped, _ := xmlFilter(value)
+		return xmlAttributeWhiteSpaceReplacer.Replace(escaped), nil
+
+	case xmlCDATAContext:
+		return strings.ReplaceAll(value, "]]>", "]]]]><![CDATA[>"), nil
+
+	case xmlCommentContext:
+		if strings.Contains(value, "--") {
+			return "", errors.New("value can't be written into an XML comment, it contains --")
+		}
+		return value, nil
+	}
+	return value, nil
+}
+
+//
+// Escapes value for a .properties file as java.util.Properties reads it, characters outside of ASCII included.
+// Leading white space is escaped at the beginning of a key or value, where it would be dropped otherwise.
+func escapeProperty(value string, isKey bool, atStart bool) string {
+	var builder strings.Builder
+	for i, r := range value {
+		switch {
+		case r == '\\':
+			builder.WriteString(`\\`)
+		case r == '\n':
+			builder.WriteString(`\n`)
+		case r == '\r':
+			builder.WriteString(`\r`)
+		case r == '\t':
+			builder.WriteString(`\t`)
+		case r == '\f':
+			builder.WriteString(`\f`)
+		case r == ' ' && (isKey || (atStart && i == 0)):
+			builder.WriteString(`\ `)
+		case isKey && strings.ContainsRune("=:#!", r):
+			builder.WriteRune('\\')
+			builder.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16Units(r) {
+				builder.WriteString(`\u`)
+				hex := strconv.FormatUint(uint64(unit), 16)
+				builder.WriteString(strings.Repeat("0", 4-len(hex)) + hex)
+			}
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+func utf16Units(r rune) []uint16 {
+	if r < 0x10000 {
+		return []uint16{uint16(r)}
+	}
+	r -= 0x10000
+	return []uint16{uint16(0xd800 + (r >> 10)), uint16(0xdc00 + (r & 0x3ff))}
+}
+
+var propertiesUnicodeEscape = regexp.MustCompile(`\\u[0-9a-fA-F]{4}`)
+
+//
+// Checks that a resolved document is well-formed in its format
+func validateDocument(format DocumentFormat, text string) error {
+	var err error
+	line := 0
+
+	switch format {
+	case DocumentFormatJSON:
+		var document interface{}
+		if err = json.Unmarshal([]byte(text), &document); err != nil {
+			var syntaxError *json.SyntaxError
+			if errors.As(err, &syntaxError) {
+				line = lineOfOffset(text, int(syntaxError.Offset))
+			}
+		}
+
+	case DocumentFormatYAML:
+		decoder := yaml.NewDecoder(strings.NewReader(text))
+		for {
+			var document yaml.Node
+			if err = decoder.Decode(&document); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				break
+			}
+		}
+
+	case DocumentFormatTOML:
+		var document map[string]interface{}
+		if _, err = toml.Decode(text, &document); err != nil {
+			var parseError toml.ParseError
+			if errors.As(err, &parseError) {
+				line = parseError.Position.Line
+			}
+		}
+
+	case DocumentFormatProperties:
+		if !utf8.ValidString(text) {
+			err = errors.New("invalid UTF-8")
+		}
+		for i, textLine := range strings.Split(text, "\n") {
+			stripped := propertiesUnicodeEscape.ReplaceAllString(strings.ReplaceAll(textLine, `\\`, ""), "")
+			if strings.Contains(stripped, `\u`) {
+				err = errors.New(`malformed \uXXXX escape`)
+				line = i + 1
+				break
+			}
+		}
+
+	case DocumentFormatXML:
+		decoder := xml.NewDecoder(strings.NewReader(text))
+		for {
+			if _, err = decoder.Token(); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				var syntaxError *xml.SyntaxError
+				if errors.As(err, &syntaxError) {
+					line = syntaxError.Line
+				}
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return &MalformedDocumentError{Format: format, Line: line, Err: err}
+	}
+	return nil
+}
+
+func lineOfOffset(text string, offset int) int {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	return strings.Count(text[:offset], "\n") + 1
+}
@@ -0,0 +1,226 @@
+package resolver
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// Value that breaks every format when it is pasted as is
+const nastyValue = "p@ss \"word\" it's\\ <b>&</b> ]]> -- ''' # x: y\nsecond line"
+
+func newNastyServiceMock() ServiceMockedObjectWithRecords {
+	return NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/value": {Name: "/app/value", Type: stringType, Value: nastyValue},
+		"ssm:/app/port":  {Name: "/app/port", Type: stringType, Value: "5432"},
+	})
+}
+
+func TestAutoEscapingJSON(t *testing.T) {
+	serviceObject := newNastyServiceMock()
+	text := `{"value": "{{ssm:/app/value}}", "port": {{ssm:/app/port}}, "url": "db://{{ssm:/app/value}}/x"}`
+
+	output, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{DocumentFormat: DocumentFormatJSON})
+	assert.Nil(t, err)
+
+	document := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(output), &document))
+	assert.Equal(t, nastyValue, document["value"])
+	assert.Equal(t, float64(5432), document["port"])
+	assert.Equal(t, "db://"+nastyValue+"/x", document["url"])
+
+	// without escaping the document breaks
+	_, err = ResolveParametersInText(&serviceObject, `{"value": {{ssm:/app/value}}}`, ResolveOptions{DocumentFormat: DocumentFormatJSON})
+	var malformedDocumentError *MalformedDocumentError
+	assert.True(t, errors.As(err, &malformedDocumentError))
+	assert.Equal(t, DocumentFormatJSON, malformedDocumentError.Format)
+	assert.Equal(t, 1, malformedDocumentError.Line)
+}
+
+func TestAutoEscapingYAML(t *testing.T) {
+	serviceObject := newNastyServiceMock()
+	text := "# {{ssm:/app/port}} in a comment\n" +
+		"plain: {{ssm:/app/value}}\n" +
+		"double: \"{{ssm:/app/value}}\"\n" +
+		"port: {{ssm:/app/port}}\n" +
+		"list:\n  - {{ssm:/app/value}}\n" +
+		"block: |\n  first\n  {{ssm:/app/value}}\n" +
+		"after: done\n"
+
+	output, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{DocumentFormat: DocumentFormatYAML})
+	assert.Nil(t, err)
+
+	document := map[string]interface{}{}
+	assert.Nil(t, yaml.Unmarshal([]byte(output), &document))
+	assert.Equal(t, nastyValue, document["plain"])
+	assert.Equal(t, nastyValue, document["double"])
+	assert.Equal(t, "5432", document["port"])
+	assert.Equal(t, []interface{}{nastyValue}, document["list"])
+	assert.Equal(t, "first\n"+nastyValue+"\n", document["block"])
+	assert.Equal(t, "done", document["after"])
+	assert.Contains(t, output, "# 5432 in a comment")
+
+	// single-quoted scalars have no escape for line breaks
+	_, err = ResolveParametersInText(&serviceObject, "single: '{{ssm:/app/value}}'", ResolveOptions{DocumentFormat: DocumentFormatYAML})
+	var malformedDocumentError *MalformedDocumentError
+	assert.True(t, errors.As(err, &malformedDocumentError))
+
+	output, err = ResolveParametersInText(&serviceObject, "single: 'it''s {{ssm:/app/port}}'", ResolveOptions{DocumentFormat: DocumentFormatYAML})
+	assert.Nil(t, err)
+	assert.Equal(t, "single: 'it''s 5432'", output)
+}
+
+func TestAutoEscapingYAMLPlainValues(t *testing.T) {
+	values := []string{"*foo", "@x", "%x", "- x", "-", "a,b", "[a]", "{a}", "a: b", "a:", "a #b", "&a", "!a", "?x", "|x", ">x", "'x", "\"x", "`x",
+		"null", "Null", "~", "", "yes", "No", "on", "true", "False", "42", "-1", "0x1F", "3.5", ".inf", "2001-12-14", "value "}
+	for _, value := range values {
+		serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+			"ssm:/a": {Name: "/a", Type: stringType, Value: value},
+		})
+
+		output, err := ResolveParametersInText(&serviceObject, "k: {{ssm:/a}}\nl: [{{ssm:/a}}, z]\n", ResolveOptions{DocumentFormat: DocumentFormatYAML})
+		assert.Nil(t, err, value)
+
+		document := map[string]interface{}{}
+		assert.Nil(t, yaml.Unmarshal([]byte(output), &document), value)
+		assert.Equal(t, value, document["k"], value)
+		assert.Equal(t, []interface{}{value, "z"}, document["l"], value)
+	}
+
+	// values that read as the same string stay plain
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/a": {Name: "/a", Type: stringType, Value: "db.example.com/x-1 (primary)"},
+	})
+	output, err := ResolveParametersInText(&serviceObject, "k: {{ssm:/a}}", ResolveOptions{DocumentFormat: DocumentFormatYAML})
+	assert.Nil(t, err)
+	assert.Equal(t, "k: db.example.com/x-1 (primary)", output)
+
+	// in the middle of a plain scalar values are written as is, or rejected when they would break it
+	for value, expected := range map[string]string{
+		"5432": "url: http://h:5432/x\nl: [pre-5432-post, z]\n",
+		"db:1": "url: http://h:db:1/x\nl: [pre-db:1-post, z]\n",
+		"null": "url: http://h:null/x\nl: [pre-null-post, z]\n",
+	} {
+		serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+			"ssm:/a": {Name: "/a", Type: stringType, Value: value},
+		})
+		output, err := ResolveParametersInText(&serviceObject, "url: http://h:{{ssm:/a}}/x\nl: [pre-{{ssm:/a}}-post, z]\n", ResolveOptions{DocumentFormat: DocumentFormatYAML})
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, output, value)
+	}
+
+	for _, value := range []string{"a: b", "a #b", "a\nb", "a,b", "x:"} {
+		serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+			"ssm:/a": {Name: "/a", Type: stringType, Value: value},
+		})
+		for _, text := range []string{"k: pre-{{ssm:/a}}-post", "k: [pre-{{ssm:/a}}, z]"} {
+			_, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{DocumentFormat: DocumentFormatYAML})
+			var malformedDocumentError *MalformedDocumentError
+			if value == "a,b" && text == "k: pre-{{ssm:/a}}-post" {
+				assert.Nil(t, err)
+				continue
+			}
+			assert.True(t, errors.As(err, &malformedDocumentError), value+" in "+text)
+		}
+	}
+}
+
+func TestAutoEscapingTOML(t *testing.T) {
+	serviceObject := newNastyServiceMock()
+	text := "# {{ssm:/app/port}}\nvalue = \"{{ssm:/app/value}}\"\nmulti = \"\"\"\n{{ssm:/app/value}}\"\"\"\nport = {{ssm:/app/port}}\n"
+
+	output, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{DocumentFormat: DocumentFormatTOML})
+	assert.Nil(t, err)
+
+	document := map[string]interface{}{}
+	_, err = toml.Decode(output, &document)
+	assert.Nil(t, err)
+	assert.Equal(t, nastyValue, document["value"])
+	assert.Equal(t, nastyValue, document["multi"])
+	assert.Equal(t, int64(5432), document["port"])
+
+	// literal strings have no escapes
+	_, err = ResolveParametersInText(&serviceObject, "value = '{{ssm:/app/value}}'\n", ResolveOptions{DocumentFormat: DocumentFormatTOML})
+	var malformedDocumentError *MalformedDocumentError
+	assert.True(t, errors.As(err, &malformedDocumentError))
+}
+
+func TestAutoEscapingProperties(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/value": {Name: "/app/value", Type: stringType, Value: " a\\b\nc=d é"},
+		"ssm:/app/key":   {Name: "/app/key", Type: stringType, Value: "db url"},
+	})
+	text := "# {{ssm:/app/value}}\n{{ssm:/app/key}}={{ssm:/app/value}}\nplain = x{{ssm:/app/value}}\n"
+
+	output, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{DocumentFormat: DocumentFormatProperties})
+	assert.Nil(t, err)
+	assert.Equal(t, "#  a\\b\nc=d é\ndb\\ url=\\ a\\\\b\\nc=d \\u00e9\nplain = x a\\\\b\\nc=d \\u00e9\n", output)
+}
+
+func TestAutoEscapingXML(t *testing.T) {
+	serviceObject := newNastyServiceMock()
+	text := `<config name="{{ssm:/app/value}}" alt='{{ssm:/app/value}}'><value>{{ssm:/app/value}}</value>` +
+		`<raw><![CDATA[{{ssm:/app/value}}]]></raw></config>`
+
+	output, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{DocumentFormat: DocumentFormatXML})
+	assert.Nil(t, err)
+
+	document := struct {
+		Name  string `xml:"name,attr"`
+		Alt   string `xml:"alt,attr"`
+		Value string `xml:"value"`
+		Raw   string `xml:"raw"`
+	}{}
+	assert.Nil(t, xml.Unmarshal([]byte(output), &document))
+	assert.Equal(t, nastyValue, document.Name)
+	assert.Equal(t, document.Name, document.Alt)
+	assert.Equal(t, nastyValue, document.Value)
+	assert.Equal(t, nastyValue, document.Raw)
+
+	// comments can't hold --
+	_, err = ResolveParametersInText(&serviceObject, "<a><!-- {{ssm:/app/value}} --></a>", ResolveOptions{DocumentFormat: DocumentFormatXML})
+	var malformedDocumentError *MalformedDocumentError
+	assert.True(t, errors.As(err, &malformedDocumentError))
+}
+
+func TestPlaceholdersWithFiltersAreNotEscaped(t *testing.T) {
+	serviceObject := newNastyServiceMock()
+
+	output, err := ResolveParametersInText(&serviceObject, `{"value": "{{ssm:/app/value | base64}}"}`, ResolveOptions{DocumentFormat: DocumentFormatJSON})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"value": "cEBzcyAid29yZCIgaXQnc1wgPGI+JjwvYj4gXV0+IC0tICcnJyAjIHg6IHkKc2Vjb25kIGxpbmU="}`, output)
+}
+
+func TestDetectDocumentFormat(t *testing.T) {
+	assert.Equal(t, DocumentFormatYAML, detectDocumentFormat(DocumentFormatAuto, "{}", "config.tmpl", "config.YML"))
+	assert.Equal(t, DocumentFormatTOML, detectDocumentFormat(DocumentFormatAuto, "", "app.toml"))
+	assert.Equal(t, DocumentFormatJSON, detectDocumentFormat(DocumentFormatAuto, "  {\"a\": 1}"))
+	assert.Equal(t, DocumentFormatXML, detectDocumentFormat(DocumentFormatAuto, "<?xml version=\"1.0\"?><a/>"))
+	assert.Equal(t, DocumentFormatNone, detectDocumentFormat(DocumentFormatAuto, "{{ssm:/a}}"))
+	assert.Equal(t, DocumentFormatProperties, detectDocumentFormat(DocumentFormatProperties, "", "config.json"))
+}
+
+func TestResolveParametersInFileWithAutoEscaping(t *testing.T) {
+	serviceObject := newNastyServiceMock()
+	directory := t.TempDir()
+	inputFileName := filepath.Join(directory, "config.json")
+	outputFileName := filepath.Join(directory, "config.resolved")
+	assert.Nil(t, os.WriteFile(inputFileName, []byte(`{"value": "{{ssm:/app/value}}"}`), 0600))
+
+	err := ResolveParametersInFile(&serviceObject, inputFileName, outputFileName, ResolveOptions{DocumentFormat: DocumentFormatAuto})
+	assert.Nil(t, err)
+
+	output, err := os.ReadFile(outputFileName)
+	assert.Nil(t, err)
+	document := map[string]string{}
+	assert.Nil(t, json.Unmarshal(output, &document))
+	assert.Equal(t, nastyValue, document["value"])
+}
@@ -0,0 +1,101 @@
+package resolver
+
+import (
+	"sort"
+	"strings"
+)
+
+type InvalidParameterReference struct {
+	Reference string
+	Reason    string
+}
+
+//
+// Returned when parameter references don't follow the naming rules of their store.
+// It lists every offending reference, not just the first one.
+type InvalidParameterReferenceError struct {
+	References []InvalidParameterReference
+	// where the references appear, when they come from a document
+	Positions map[string][]PlaceholderPosition
+}
+
+func (e *InvalidParameterReferenceError) Error() string {
+	descriptions := []string{}
+	for _, ref := range e.References {
+		description := "{{" + ref.Reference + "}}"
+		if positions := e.Positions[ref.Reference]; len(positions) > 0 {
+			description += " at " + positionsDescription(positions)
+		}
+		descriptions = append(descriptions, description+" ("+ref.Reason+")")
+	}
+	return "invalid parameter reference(s): " + strings.Join(descriptions, ", ")
+}
+
+//
+// Returned by parameter providers when some of the requested references don't exist.
+// Providers return it together with the parameters they were able to resolve.
+type MissingParametersError struct {
+	References []string
+}
+
+func (e *MissingParametersError) Error() string {
+	return "The following parameter(s) cannot be resolved: " + strings.Join(e.References, ",")
+}
+
+//
+// A parameter whose type doesn't match the prefix it was referenced with, e.g. ssm: for a SecureString
+type ParameterTypeMismatch struct {
+	Reference string
+	Prefix    string
+	Type      string
+}
+
+func (m ParameterTypeMismatch) String() string {
+	switch m.Prefix {
+	case ssmSecurePrefix:
+		return "for parameter reference {{" + m.Reference + "}} secure prefix " + m.Prefix + " is used for a non-secure type " + m.Type
+	case ssmNonSecurePrefix:
+		return "for parameter reference {{" + m.Reference + "}} non-secure prefix " + m.Prefix + " is used for a secure type " + m.Type
+	case ssmListPrefix:
+		return "for parameter reference {{" + m.Reference + "}} list prefix " + m.Prefix + " is used for a non-list type " + m.Type
+	}
+	return "for parameter reference {{" + m.Reference + "}} prefix " + m.Prefix + " is used for an unexpected type " + m.Type
+}
+
+//
+// Returned by the resolve functions when some references could not be resolved or have the wrong type.
+// It lists every missing reference and every type mismatch over all batches.
+type ParameterResolutionError struct {
+	MissingReferences []string
+	TypeMismatches    []ParameterTypeMismatch
+	// where the missing and mismatched references appear, when they come from a document
+	Positions map[string][]PlaceholderPosition
+}
+
+func (e *ParameterResolutionError) Error() string {
+	withPositions := func(description string, reference string) string {
+		if positions := e.Positions[reference]; len(positions) > 0 {
+			return description + " (at " + positionsDescription(positions) + ")"
+		}
+		return description
+	}
+
+	descriptions := []string{}
+	if len(e.MissingReferences) > 0 {
+		missingReferences := []string{}
+		for _, ref := range e.MissingReferences {
+			missingReferences = append(missingReferences, withPositions(ref, ref))
+		}
+		descriptions = append(descriptions, (&MissingParametersError{References: missingReferences}).Error())
+	}
+	for _, mismatch := range e.TypeMismatches {
+		descriptions = append(descriptions, withPositions(mismatch.String(), mismatch.Reference))
+	}
+	return strings.Join(descriptions, "; ")
+}
+
+func newMissingParametersError(references []string) *MissingParametersError {
+	sorted := append([]string{}, references...)
+	sort.Strings(sorted)
+	return &MissingParametersError{References: sorted}
+}
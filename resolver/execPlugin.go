@@ -0,0 +1,244 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//
+// Version of the exec plugin protocol sent in every request
+const execPluginProtocolVersion = 1
+
+//
+// Timeout of one plugin run unless ExecPluginProvider.Timeout is set
+const defaultExecPluginTimeout = 30 * time.Second
+
+//
+// Only the tail of the plugin's stderr is kept for error messages
+const maxExecPluginStderrLength = 4096
+
+//
+// ExecPluginProvider resolves references with a custom prefix, e.g. {{corp:db/password}},
+// by running an external executable once per batch of references.
+//
+// The executable receives a JSON request on stdin:
+//
+//	{"version": 1, "references": ["corp:db/password", "corp:db/user"]}
+//
+// and writes a JSON response to stdout:
+//
+//	{
+//	  "parameters": {"corp:db/password": {"name": "db/password", "type": "SecureString", "value": "p@ss", "version": 3}},
+//	  "missing":    ["corp:db/user"],
+//	  "invalid":    [{"reference": "corp:bad name", "reason": "spaces are not allowed"}]
+//	}
+//
+// References the response doesn't mention are reported as missing. The type defaults to String.
+// A non-zero exit code or a run longer than Timeout fails the whole resolution with an ExecPluginError.
+type ExecPluginProvider struct {
+	// Prefixes served by the plugin, e.g. {Prefix: "corp:", Secure: true}
+	Prefixes []ReferencePrefix
+	Command  string
+	Args     []string
+	// Extra environment variables (KEY=value) added to the environment of the resolver process
+	Env []string
+	// 30 seconds if not set
+	Timeout time.Duration
+}
+
+var _ IPrefixedParameterProvider = (*ExecPluginProvider)(nil)
+
+func NewExecPluginProvider(prefix ReferencePrefix, command string, args ...string) *ExecPluginProvider {
+	return &ExecPluginProvider{Prefixes: []ReferencePrefix{prefix}, Command: command, Args: args}
+}
+
+func (p *ExecPluginProvider) ReferencePrefixes() []ReferencePrefix {
+	return append([]ReferencePrefix{}, p.Prefixes...)
+}
+
+//
+// Returned when the plugin executable fails, times out or writes a malformed response
+type ExecPluginError struct {
+	Command string
+	// Exit code of the plugin, -1 if it didn't exit on its own
+	ExitCode int
+	// Tail of the plugin's stderr
+	Stderr string
+	Err    error
+}
+
+func (e *ExecPluginError) Error() string {
+	message := "parameter plugin " + e.Command + " failed"
+	if e.ExitCode > 0 {
+		message += " with exit code " + strconv.Itoa(e.ExitCode)
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	if e.Stderr != "" {
+		message += ", stderr: " + e.Stderr
+	}
+	return message
+}
+
+func (e *ExecPluginError) Unwrap() error {
+	return e.Err
+}
+
+type execPluginRequest struct {
+	Version    int      `json:"version"`
+	References []string `json:"references"`
+}
+
+type execPluginParameter struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Version int64  `json:"version"`
+	ARN     string `json:"arn"`
+}
+
+type execPluginResponse struct {
+	Parameters map[string]execPluginParameter `json:"parameters"`
+	Missing    []string                       `json:"missing"`
+	Invalid    []struct {
+		Reference string `json:"reference"`
+		Reason    string `json:"reason"`
+	} `json:"invalid"`
+}
+
+func (p *ExecPluginProvider) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	response, err := p.run(ctx, parameterReferences)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Invalid) > 0 {
+		invalidReferences := []InvalidParameterReference{}
+		for _, invalid := range response.Invalid {
+			invalidReferences = append(invalidReferences, InvalidParameterReference{Reference: invalid.Reference, Reason: invalid.Reason})
+		}
+		sort.Slice(invalidReferences, func(i, j int) bool { return invalidReferences[i].Reference < invalidReferences[j].Reference })
+		return nil, &InvalidParameterReferenceError{References: invalidReferences}
+	}
+
+	reportedMissing := map[string]bool{}
+	for _, ref := range response.Missing {
+		reportedMissing[ref] = true
+	}
+
+	// values for references that were not requested are ignored
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+	for _, ref := range parameterReferences {
+		parameter, found := response.Parameters[ref]
+		if !found || reportedMissing[ref] {
+			missingReferences = append(missingReferences, ref)
+			continue
+		}
+
+		if parameter.Type == "" {
+			parameter.Type = stringType
+		}
+		result[ref] = SsmParameterInfo{
+			Name:    parameter.Name,
+			Type:    parameter.Type,
+			Value:   parameter.Value,
+			Version: parameter.Version,
+			ARN:     parameter.ARN,
+		}
+	}
+
+	if len(missingReferences) > 0 {
+		return result, newMissingParametersError(missingReferences)
+	}
+	return result, nil
+}
+
+//
+// Runs the plugin for one batch of references and decodes its response
+func (p *ExecPluginProvider) run(ctx context.Context, parameterReferences []string) (*execPluginResponse, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultExecPluginTimeout
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	input, err := json.Marshal(execPluginRequest{Version: execPluginProtocolVersion, References: parameterReferences})
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	stderr := &tailWriter{maxLength: maxExecPluginStderrLength}
+	command := exec.CommandContext(runCtx, p.Command, p.Args...)
+	command.Stdin = bytes.NewReader(input)
+	command.Stdout = &stdout
+	command.Stderr = stderr
+	if len(p.Env) > 0 {
+		command.Env = append(os.Environ(), p.Env...)
+	}
+	// children of a killed plugin may keep its output open, don't wait for them
+	command.WaitDelay = time.Second
+
+	newError := func(err error) *ExecPluginError {
+		pluginError := &ExecPluginError{Command: p.Command, ExitCode: -1, Stderr: stderr.String(), Err: err}
+		if command.ProcessState != nil {
+			pluginError.ExitCode = command.ProcessState.ExitCode()
+		}
+		return pluginError
+	}
+
+	if err := command.Run(); err != nil {
+		// the caller's own cancellation is reported as is, so batches stop the usual way
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return nil, newError(errors.New("timed out after " + timeout.String()))
+		}
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) {
+			return nil, newError(nil)
+		}
+		return nil, newError(err)
+	}
+
+	response := &execPluginResponse{}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, newError(errors.New("malformed response: " + err.Error()))
+	}
+	return response, nil
+}
+
+//
+// Writer that keeps only the last maxLength bytes written to it
+type tailWriter struct {
+	maxLength int
+	tail      []byte
+}
+
+func (w *tailWriter) Write(data []byte) (int, error) {
+	written := len(data)
+	if len(data) >= w.maxLength {
+		w.tail = append(w.tail[:0], data[len(data)-w.maxLength:]...)
+		return written, nil
+	}
+	if overflow := len(w.tail) + len(data) - w.maxLength; overflow > 0 {
+		w.tail = append(w.tail[:0], w.tail[overflow:]...)
+	}
+	w.tail = append(w.tail, data...)
+	return written, nil
+}
+
+func (w *tailWriter) String() string {
+	return string(w.tail)
+}
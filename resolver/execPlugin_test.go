@@ -0,0 +1,131 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const execPluginModeEnvVariable = "RESOLVER_TEST_EXEC_PLUGIN_MODE"
+
+// Not a real test: runs as the plugin executable when the test binary is started by newTestExecPlugin
+func TestExecPluginHelperProcess(t *testing.T) {
+	mode := os.Getenv(execPluginModeEnvVariable)
+	if mode == "" {
+		return
+	}
+
+	request := execPluginRequest{}
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, "cannot read request:", err)
+		os.Exit(2)
+	}
+
+	switch mode {
+	case "fail":
+		fmt.Fprintln(os.Stderr, "corp vault is unreachable")
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Minute)
+	case "garbage":
+		fmt.Print("not json")
+		os.Exit(0)
+	}
+
+	response := execPluginResponse{Parameters: map[string]execPluginParameter{}}
+	for _, ref := range request.References {
+		name := strings.TrimPrefix(ref, "corp:")
+		switch {
+		case strings.HasPrefix(name, "missing"):
+			response.Missing = append(response.Missing, ref)
+		case strings.HasPrefix(name, "secure/"):
+			response.Parameters[ref] = execPluginParameter{Name: name, Type: secureStringType, Value: "secret-" + name}
+		case strings.HasPrefix(name, "silent"):
+			// not mentioned in the response at all
+		default:
+			response.Parameters[ref] = execPluginParameter{Name: name, Value: "value-" + name, Version: 7}
+		}
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(response)
+	os.Exit(0)
+}
+
+func newTestExecPlugin(mode string) *ExecPluginProvider {
+	provider := NewExecPluginProvider(ReferencePrefix{Prefix: "corp:", Secure: false}, os.Args[0], "-test.run=^TestExecPluginHelperProcess$")
+	provider.Env = []string{execPluginModeEnvVariable + "=" + mode}
+	return provider
+}
+
+func TestExecPluginProviderGetParameters(t *testing.T) {
+	result, err := newTestExecPlugin("ok").GetParameters(context.Background(), []string{
+		"corp:db/user", "corp:secure/password", "corp:missing/key", "corp:silent/key",
+	})
+
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, []string{"corp:missing/key", "corp:silent/key"}, missingParametersError.References)
+	assert.Equal(t, SsmParameterInfo{Name: "db/user", Type: stringType, Value: "value-db/user", Version: 7}, result["corp:db/user"])
+	assert.Equal(t, secureStringType, result["corp:secure/password"].Type)
+}
+
+func TestExecPluginProviderFailures(t *testing.T) {
+	_, err := newTestExecPlugin("fail").GetParameters(context.Background(), []string{"corp:db/user"})
+	var pluginError *ExecPluginError
+	assert.True(t, errors.As(err, &pluginError))
+	assert.Equal(t, 3, pluginError.ExitCode)
+	assert.Equal(t, "corp vault is unreachable\n", pluginError.Stderr)
+
+	_, err = newTestExecPlugin("garbage").GetParameters(context.Background(), []string{"corp:db/user"})
+	assert.True(t, errors.As(err, &pluginError))
+	assert.Contains(t, pluginError.Error(), "malformed response")
+
+	provider := newTestExecPlugin("hang")
+	provider.Timeout = 200 * time.Millisecond
+	_, err = provider.GetParameters(context.Background(), []string{"corp:db/user"})
+	assert.True(t, errors.As(err, &pluginError))
+	assert.Contains(t, pluginError.Error(), "timed out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = newTestExecPlugin("ok").GetParameters(ctx, []string{"corp:db/user"})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestResolveParametersInTextWithExecPlugin(t *testing.T) {
+	ssmService := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/host": {Name: "/app/host", Type: stringType, Value: "db.example.com"},
+	})
+	router := NewProviderRouter().Register(&ssmService).Register(newTestExecPlugin("ok"))
+
+	// more references than fit in one batch, so the plugin runs once per batch
+	input := "{{ssm:/app/host}}"
+	expected := "db.example.com"
+	for i := 0; i < 2*maxParametersRetrievedFromSsm+1; i++ {
+		input += fmt.Sprintf(" {{corp:key%d}}", i)
+		expected += fmt.Sprintf(" value-key%d", i)
+	}
+
+	output, err := ResolveParametersInTextWithContext(context.Background(), router, input, ResolveOptions{MaxConcurrentRequests: 3})
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, output)
+}
+
+func TestTailWriterKeepsTheTail(t *testing.T) {
+	writer := &tailWriter{maxLength: 8}
+
+	for _, chunk := range []string{"abc", "defgh", "ij", strings.Repeat("x", 20) + "12345678", "9"} {
+		written, err := writer.Write([]byte(chunk))
+		assert.Nil(t, err)
+		assert.Equal(t, len(chunk), written)
+		assert.True(t, len(writer.tail) <= 8)
+	}
+	assert.Equal(t, "23456789", writer.String())
+}
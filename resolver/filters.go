@@ -0,0 +1,161 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//
+// PlaceholderFilter transforms a parameter value before it is written into a document.
+// Filters are applied in the order they are listed: {{ssm:/db/password | base64decode | json}}.
+type PlaceholderFilter func(value string) (string, error)
+
+var placeholderFilterName = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_\-]*$`)
+
+var placeholderFiltersMutex sync.RWMutex
+
+//
+// Built-in filters:
+//
+//	json          - escapes the value for use inside a JSON string, without the quotes
+//	yamlstr       - renders the value as a double-quoted YAML scalar, including the quotes
+//	xml           - escapes the value for XML text and attribute values
+//	shellquote    - renders the value as a single-quoted POSIX shell word, including the quotes
+//	urlencode     - escapes the value for use in a URL query
+//	base64        - encodes the value with standard base64
+//	base64decode  - decodes a standard base64 value
+var placeholderFilters = map[string]PlaceholderFilter{
+	"json":         jsonStringFilter,
+	"yamlstr":      yamlStringFilter,
+	"xml":          xmlFilter,
+	"shellquote":   shellQuoteFilter,
+	"urlencode":    urlEncodeFilter,
+	"base64":       base64EncodeFilter,
+	"base64decode": base64DecodeFilter,
+}
+
+//
+// Makes filter available in placeholders under name. Names are letters, numbers, _ and -
+// beginning with a letter; a name can only be registered once.
+func RegisterPlaceholderFilter(name string, filter PlaceholderFilter) error {
+	if !placeholderFilterName.MatchString(name) {
+		return errors.New("invalid filter name " + name)
+	}
+	if filter == nil {
+		return errors.New("filter " + name + " is nil")
+	}
+
+	placeholderFiltersMutex.Lock()
+	defer placeholderFiltersMutex.Unlock()
+
+	if _, found := placeholderFilters[name]; found {
+		return errors.New("filter " + name + " is already registered")
+	}
+	placeholderFilters[name] = filter
+	return nil
+}
+
+func lookupPlaceholderFilter(name string) (PlaceholderFilter, bool) {
+	placeholderFiltersMutex.RLock()
+	defer placeholderFiltersMutex.RUnlock()
+
+	filter, found := placeholderFilters[name]
+	return filter, found
+}
+
+//
+// Returned when a filter fails on the value of a placeholder, e.g. base64decode on a value that isn't base64
+type PlaceholderFilterError struct {
+	Reference string
+	Filter    string
+	Err       error
+	// where the placeholder appears, the zero value outside of documents
+	Position PlaceholderPosition
+}
+
+func (e *PlaceholderFilterError) Error() string {
+	position := ""
+	if e.Position.Line > 0 {
+		position = " at " + e.Position.String()
+	}
+	return "filter " + e.Filter + " failed for parameter reference {{" + e.Reference + "}}" + position + ": " + e.Err.Error()
+}
+
+func (e *PlaceholderFilterError) Unwrap() error {
+	return e.Err
+}
+
+//
+// Applies the named filters in order
+func applyPlaceholderFilters(reference string, value string, filterNames []string) (string, error) {
+	for _, name := range filterNames {
+		filter, found := lookupPlaceholderFilter(name)
+		if !found {
+			return "", &PlaceholderFilterError{Reference: reference, Filter: name, Err: errors.New("unknown filter")}
+		}
+
+		var err error
+		if value, err = filter(value); err != nil {
+			return "", &PlaceholderFilterError{Reference: reference, Filter: name, Err: err}
+		}
+	}
+	return value, nil
+}
+
+func jsonStringFilter(value string) (string, error) {
+	quoted, err := jsonString(value)
+	if err != nil {
+		return "", err
+	}
+	return quoted[1 : len(quoted)-1], nil
+}
+
+//
+// Returns value as a quoted JSON string. Unlike json.Marshal, <, > and & are kept as they are.
+func jsonString(value string) (string, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+//
+// YAML double-quoted scalars accept every JSON string
+func yamlStringFilter(value string) (string, error) {
+	return jsonString(value)
+}
+
+var xmlReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+func xmlFilter(value string) (string, error) {
+	return xmlReplacer.Replace(value), nil
+}
+
+func shellQuoteFilter(value string) (string, error) {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'", nil
+}
+
+func urlEncodeFilter(value string) (string, error) {
+	return url.QueryEscape(value), nil
+}
+
+func base64EncodeFilter(value string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(value)), nil
+}
+
+func base64DecodeFilter(value string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,88 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltInPlaceholderFilters(t *testing.T) {
+	value := "p@ss \"word\"\n<it's> & $HOME"
+	testCases := map[string]string{
+		"json":       `p@ss \"word\"\n<it's> & $HOME`,
+		"yamlstr":    `"p@ss \"word\"\n<it's> & $HOME"`,
+		"xml":        "p@ss &quot;word&quot;\n&lt;it&apos;s&gt; &amp; $HOME",
+		"shellquote": `'p@ss "word"` + "\n" + `<it'\''s> & $HOME'`,
+		"urlencode":  "p%40ss+%22word%22%0A%3Cit%27s%3E+%26+%24HOME",
+		"base64":     "cEBzcyAid29yZCIKPGl0J3M+ICYgJEhPTUU=",
+	}
+	for name, expected := range testCases {
+		output, err := applyPlaceholderFilters("ssm:/a", value, []string{name})
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, output, name)
+	}
+
+	output, err := applyPlaceholderFilters("ssm:/a", value, []string{"base64", "base64decode"})
+	assert.Nil(t, err)
+	assert.Equal(t, value, output)
+
+	_, err = applyPlaceholderFilters("ssm:/a", "not base64!", []string{"base64decode"})
+	var filterError *PlaceholderFilterError
+	assert.True(t, errors.As(err, &filterError))
+	assert.Equal(t, "base64decode", filterError.Filter)
+}
+
+func TestRegisterPlaceholderFilter(t *testing.T) {
+	identity := func(value string) (string, error) { return value, nil }
+	// the registry is global, the filter is still there when the test runs again with -count
+	if _, found := lookupPlaceholderFilter("test-upper"); !found {
+		assert.Nil(t, RegisterPlaceholderFilter("test-upper", func(value string) (string, error) {
+			return strings.ToUpper(value), nil
+		}))
+	}
+	assert.NotNil(t, RegisterPlaceholderFilter("test-upper", identity))
+	assert.NotNil(t, RegisterPlaceholderFilter("json", identity))
+	assert.NotNil(t, RegisterPlaceholderFilter("1bad name", identity))
+	assert.NotNil(t, RegisterPlaceholderFilter("test-nil", nil))
+
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/name": {Name: "/app/name", Type: stringType, Value: "demo"},
+	})
+	output, err := ResolveParametersInText(&serviceObject, "{{ssm:/app/name | test-upper | base64}}", ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "REVNTw==", output)
+}
+
+func TestResolveParametersInTextWithFilters(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm-secure:param2": {Name: "param2", Type: secureStringType, Value: "multi\nline \"secret\""},
+	})
+
+	text := `{"secure-parameter-1" : "{{ssm-secure:param2 | json}}", "flag" : "{{ssm:/flags/x || "a\"b" | json}}"}`
+	output, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{})
+	assert.Nil(t, err)
+
+	document := map[string]string{}
+	assert.Nil(t, json.Unmarshal([]byte(output), &document))
+	assert.Equal(t, "multi\nline \"secret\"", document["secure-parameter-1"])
+	assert.Equal(t, `a"b`, document["flag"])
+}
+
+func TestMalformedPlaceholderFilters(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{})
+
+	for _, text := range []string{
+		"{{ssm:/a | nosuchfilter}}",
+		"{{ssm:/a |}}",
+		`{{ssm:/a | json || "x"}}`,
+		`{{ssm:/a || "x" || "y"}}`,
+		`{{ssm:/a || "x" json}}`,
+	} {
+		_, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{})
+		var invalidReferenceError *InvalidParameterReferenceError
+		assert.True(t, errors.As(err, &invalidReferenceError), text)
+	}
+}
@@ -0,0 +1,170 @@
+package resolver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const environmentPrefix = "env:"
+const filePrefix = "file:"
+
+var environmentVariableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+//
+// EnvironmentProvider resolves {{env:NAME}} references from the process environment,
+// e.g. to render templates locally without AWS.
+type EnvironmentProvider struct {
+	// Looks up a variable, os.LookupEnv if not set
+	LookupEnv func(name string) (string, bool)
+}
+
+var _ IPrefixedParameterProvider = (*EnvironmentProvider)(nil)
+
+func NewEnvironmentProvider() *EnvironmentProvider {
+	return &EnvironmentProvider{LookupEnv: os.LookupEnv}
+}
+
+func (p *EnvironmentProvider) ReferencePrefixes() []ReferencePrefix {
+	return []ReferencePrefix{{Prefix: environmentPrefix, Secure: false}}
+}
+
+func (p *EnvironmentProvider) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	lookupEnv := p.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+	invalidReferences := []InvalidParameterReference{}
+
+	for _, ref := range parameterReferences {
+		name := strings.TrimPrefix(ref, environmentPrefix)
+		if !environmentVariableName.MatchString(name) {
+			invalidReferences = append(invalidReferences, InvalidParameterReference{
+				Reference: ref,
+				Reason:    "environment variable name may only contain letters, numbers and _ and can't begin with a number",
+			})
+			continue
+		}
+
+		if value, found := lookupEnv(name); found {
+			result[ref] = SsmParameterInfo{Name: name, Type: stringType, Value: value}
+		} else {
+			missingReferences = append(missingReferences, ref)
+		}
+	}
+
+	return localProviderResult(result, missingReferences, invalidReferences)
+}
+
+//
+// FileProvider resolves {{file:path}} references to the content of files under BaseDir.
+// Paths are relative to BaseDir and may not leave it, neither with .. nor through symbolic links.
+type FileProvider struct {
+	BaseDir string
+	// Removes one trailing line break from the file content
+	TrimTrailingNewline bool
+}
+
+var _ IPrefixedParameterProvider = (*FileProvider)(nil)
+
+func NewFileProvider(baseDir string) *FileProvider {
+	return &FileProvider{BaseDir: baseDir}
+}
+
+func (p *FileProvider) ReferencePrefixes() []ReferencePrefix {
+	return []ReferencePrefix{{Prefix: filePrefix, Secure: false}}
+}
+
+func (p *FileProvider) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	baseDir, err := filepath.Abs(p.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+	if resolvedBaseDir, err := filepath.EvalSymlinks(baseDir); err == nil {
+		baseDir = resolvedBaseDir
+	}
+
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+	invalidReferences := []InvalidParameterReference{}
+
+	for _, ref := range parameterReferences {
+		path := strings.TrimPrefix(ref, filePrefix)
+
+		fullPath, reason := resolvePathInDirectory(baseDir, path)
+		if reason != "" {
+			invalidReferences = append(invalidReferences, InvalidParameterReference{Reference: ref, Reason: reason})
+			continue
+		}
+
+		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+			missingReferences = append(missingReferences, ref)
+			continue
+		}
+
+		if err := validateFileAndSize(fullPath); err != nil {
+			return nil, err
+		}
+
+		value, err := readTextFromFile(fullPath)
+		if err != nil {
+			return nil, err
+		}
+		if p.TrimTrailingNewline {
+			value = strings.TrimSuffix(strings.TrimSuffix(value, "\n"), "\r")
+		}
+
+		result[ref] = SsmParameterInfo{Name: path, Type: stringType, Value: value}
+	}
+
+	return localProviderResult(result, missingReferences, invalidReferences)
+}
+
+//
+// Returns the absolute path of a relative path under baseDir, or the reason it points outside of it
+func resolvePathInDirectory(baseDir string, path string) (string, string) {
+	if path == "" {
+		return "", "file path is empty"
+	}
+	if filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
+		return "", "file path must be relative to the base directory"
+	}
+
+	fullPath := filepath.Join(baseDir, path)
+	if !isInDirectory(baseDir, fullPath) {
+		return "", "file path leaves the base directory"
+	}
+
+	// a symbolic link inside the base directory may still point outside of it
+	if resolvedPath, err := filepath.EvalSymlinks(fullPath); err == nil && !isInDirectory(baseDir, resolvedPath) {
+		return "", "file path leaves the base directory"
+	}
+
+	return fullPath, ""
+}
+
+func isInDirectory(directory string, path string) bool {
+	relativePath, err := filepath.Rel(directory, path)
+	return err == nil && relativePath != ".." && !strings.HasPrefix(relativePath, ".."+string(filepath.Separator))
+}
+
+func localProviderResult(
+	result map[string]SsmParameterInfo,
+	missingReferences []string,
+	invalidReferences []InvalidParameterReference) (map[string]SsmParameterInfo, error) {
+
+	if len(invalidReferences) > 0 {
+		sort.Slice(invalidReferences, func(i, j int) bool { return invalidReferences[i].Reference < invalidReferences[j].Reference })
+		return nil, &InvalidParameterReferenceError{References: invalidReferences}
+	}
+	if len(missingReferences) > 0 {
+		return result, newMissingParametersError(missingReferences)
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvironmentProviderGetParameters(t *testing.T) {
+	t.Setenv("RESOLVER_TEST_HOST", "localhost")
+	t.Setenv("RESOLVER_TEST_EMPTY", "")
+
+	result, err := NewEnvironmentProvider().GetParameters(context.Background(), []string{
+		"env:RESOLVER_TEST_HOST",
+		"env:RESOLVER_TEST_EMPTY",
+		"env:RESOLVER_TEST_MISSING",
+	})
+
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, []string{"env:RESOLVER_TEST_MISSING"}, missingParametersError.References)
+	assert.Equal(t, SsmParameterInfo{Name: "RESOLVER_TEST_HOST", Type: stringType, Value: "localhost"}, result["env:RESOLVER_TEST_HOST"])
+	assert.Equal(t, "", result["env:RESOLVER_TEST_EMPTY"].Value)
+
+	_, err = NewEnvironmentProvider().GetParameters(context.Background(), []string{"env:1BAD-NAME"})
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+}
+
+func TestFileProviderGetParameters(t *testing.T) {
+	baseDir := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(baseDir, "certs"), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(baseDir, "certs", "ca.pem"), []byte("CERTIFICATE\n"), 0600))
+
+	provider := NewFileProvider(baseDir)
+	result, err := provider.GetParameters(context.Background(), []string{"file:certs/ca.pem", "file:certs/../certs/ca.pem"})
+	assert.Nil(t, err)
+	assert.Equal(t, "CERTIFICATE\n", result["file:certs/ca.pem"].Value)
+	assert.Equal(t, "CERTIFICATE\n", result["file:certs/../certs/ca.pem"].Value)
+
+	provider.TrimTrailingNewline = true
+	result, err = provider.GetParameters(context.Background(), []string{"file:certs/ca.pem", "file:certs/missing.pem"})
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, []string{"file:certs/missing.pem"}, missingParametersError.References)
+	assert.Equal(t, "CERTIFICATE", result["file:certs/ca.pem"].Value)
+}
+
+func TestFileProviderRejectsPathTraversal(t *testing.T) {
+	rootDir := t.TempDir()
+	baseDir := filepath.Join(rootDir, "templates")
+	assert.Nil(t, os.MkdirAll(baseDir, 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(rootDir, "secret.txt"), []byte("secret"), 0600))
+	assert.Nil(t, os.Symlink(filepath.Join(rootDir, "secret.txt"), filepath.Join(baseDir, "link.txt")))
+
+	_, err := NewFileProvider(baseDir).GetParameters(context.Background(), []string{
+		"file:../secret.txt",
+		"file:" + filepath.Join(rootDir, "secret.txt"),
+		"file:link.txt",
+		"file:",
+	})
+
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+	assert.Equal(t, 4, len(invalidReferenceError.References))
+}
+
+func TestResolveParametersInTextWithLocalAndSsmProviders(t *testing.T) {
+	t.Setenv("RESOLVER_TEST_USER", "developer")
+	baseDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(baseDir, "password.txt"), []byte("local-password"), 0600))
+
+	ssmService := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/db/host": {Name: "/app/db/host", Type: stringType, Value: "db.example.com"},
+	})
+	router := NewProviderRouter().
+		Register(&ssmService).
+		Register(NewEnvironmentProvider()).
+		Register(NewFileProvider(baseDir))
+
+	output, err := ResolveParametersInText(router,
+		"{{env:RESOLVER_TEST_USER}}:{{ file:password.txt }}@{{ssm:/app/db/host}}", ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "developer:local-password@db.example.com", output)
+}
@@ -0,0 +1,364 @@
+package resolver
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//
+// Finds parameter placeholders of the prefixes served by a provider and substitutes them.
+// A placeholder may carry a default used when its parameter doesn't exist, {{ssm:/a/b || "fallback"}},
+// or be marked optional, {{?ssm:/a/b}}, which falls back to an empty string.
+// Filters listed after the reference transform the value, {{ssm:/a/b || "fallback" | json}}.
+type placeholderScanner struct {
+	// what the reference of a placeholder may start with, e.g. ssm: or resolve:ssm:; empty when no prefix is to be matched
+	referenceStarts []string
+	prefixes        []ReferencePrefix
+	// defaults are only accepted on secure references when set
+	allowSecureDefaults bool
+}
+
+//
+// One placeholder found in a document
+type placeholder struct {
+	reference  string
+	hasDefault bool
+	// value used when the reference doesn't exist
+	defaultValue string
+	// names of the filters applied to the value
+	filters []string
+}
+
+//
+// A placeholder found by placeholderScanner.tokenize
+type placeholderToken struct {
+	// offsets of the opening braces and right after the closing braces
+	start int
+	end   int
+	// text between the braces without the optional marker and the surrounding whitespace
+	body        string
+	placeholder placeholder
+	// why the placeholder is malformed, empty if it isn't
+	reason string
+}
+
+const placeholderOpening = "{{"
+const placeholderClosing = "}}"
+
+//
+// Whitespace allowed around the reference in a placeholder
+const placeholderSpace = " \t\n\f\r"
+
+//
+// Separates a reference from its default value in a placeholder
+const placeholderDefaultSeparator = "||"
+
+//
+// Precedes every filter in a placeholder
+const placeholderFilterSeparator = "|"
+
+//
+// Marks a placeholder as optional when written right after the opening braces
+const placeholderOptionalMarker = "?"
+
+func newPlaceholderScanner(prefixes []ReferencePrefix, options ResolveOptions) *placeholderScanner {
+	scanner := &placeholderScanner{prefixes: prefixes, allowSecureDefaults: options.AllowSecureDefaults}
+	for _, prefix := range prefixes {
+		if options.IgnoreSecureParameters && prefix.Secure {
+			continue
+		}
+		scanner.referenceStarts = append(scanner.referenceStarts, prefix.Prefix)
+		if options.CloudFormationSyntax && isCloudFormationPrefix(prefix.Prefix) {
+			scanner.referenceStarts = append(scanner.referenceStarts, cloudFormationResolvePrefix+prefix.Prefix)
+		}
+	}
+	return scanner
+}
+
+//
+// Finds all placeholders of text in one pass and parses them. A placeholder is {{, optional whitespace,
+// an optional ? marker, a reference beginning with one of the prefixes and }}. Everything up to the
+// closing braces is taken as the reference and checked against the naming rules afterwards, so malformed
+// names are reported instead of skipped; braces end the placeholder unless they are part of its default.
+func (s *placeholderScanner) tokenize(text string) []placeholderToken {
+	tokens := []placeholderToken{}
+	if len(s.referenceStarts) == 0 {
+		return tokens
+	}
+
+	for position := 0; ; {
+		offset := strings.Index(text[position:], placeholderOpening)
+		if offset < 0 {
+			return tokens
+		}
+
+		token, found := s.tokenAt(text, position+offset)
+		if !found {
+			position += offset + 1
+			continue
+		}
+		tokens = append(tokens, token)
+		position = token.end
+	}
+}
+
+//
+// Returns the placeholder starting with the opening braces at start, if there is one
+func (s *placeholderScanner) tokenAt(text string, start int) (placeholderToken, bool) {
+	position := skipPlaceholderSpace(text, start+len(placeholderOpening))
+	optional := strings.HasPrefix(text[position:], placeholderOptionalMarker)
+	if optional {
+		position = skipPlaceholderSpace(text, position+len(placeholderOptionalMarker))
+	}
+
+	if !s.startsWithReference(text[position:]) {
+		return placeholderToken{}, false
+	}
+
+	bodyLength, _ := placeholderBodyLength(text[position:])
+	if bodyLength < 0 || !strings.HasPrefix(text[position+bodyLength:], placeholderClosing) {
+		return placeholderToken{}, false
+	}
+
+	token := placeholderToken{
+		start: start,
+		end:   position + bodyLength + len(placeholderClosing),
+		body:  strings.TrimRight(text[position:position+bodyLength], placeholderSpace),
+	}
+	token.placeholder, token.reason = s.parsePlaceholder(optional, token.body)
+	return token, true
+}
+
+//
+// Returns the length of the placeholder body at the start of text, i.e. the offset of the first brace,
+// or -1 when text has no brace. Braces inside the double-quoted default after || don't count.
+// unterminated is set when such a default is still open at the end of text, so more text may close it.
+func placeholderBodyLength(text string) (length int, unterminated bool) {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '{' || text[i] == '}':
+			return i, unterminated
+
+		case strings.HasPrefix(text[i:], placeholderDefaultSeparator):
+			literal := strings.TrimLeftFunc(text[i+len(placeholderDefaultSeparator):], unicode.IsSpace)
+			i = len(text) - len(literal) - 1
+			if !strings.HasPrefix(literal, "\"") {
+				continue
+			}
+			if quoted, err := strconv.QuotedPrefix(literal); err == nil {
+				i += len(quoted)
+			} else if !strings.Contains(literal, "\n") {
+				// a malformed default is reported by parsePlaceholder, its quote is an ordinary character
+				unterminated = true
+			}
+		}
+	}
+	return -1, unterminated
+}
+
+func (s *placeholderScanner) startsWithReference(text string) bool {
+	for _, referenceStart := range s.referenceStarts {
+		if strings.HasPrefix(text, referenceStart) {
+			return true
+		}
+	}
+	return false
+}
+
+func skipPlaceholderSpace(text string, position int) int {
+	for position < len(text) && strings.IndexByte(placeholderSpace, text[position]) >= 0 {
+		position++
+	}
+	return position
+}
+
+//
+// Returns the placeholder text is made of, if it is exactly one placeholder. tokens are the placeholders of text.
+func wholePlaceholder(text string, tokens []placeholderToken) (placeholderToken, bool) {
+	if len(tokens) != 1 || tokens[0].start != 0 || tokens[0].end != len(text) {
+		return placeholderToken{}, false
+	}
+	return tokens[0], true
+}
+
+//
+// Returns the deduplicated references of all placeholders in text, CloudFormation dynamic references
+// are translated into the references they stand for. Also returns the optional references: the ones
+// that have a default in every placeholder they are used in, so they may be missing.
+func (s *placeholderScanner) scan(text string) ([]string, map[string]bool, error) {
+	return s.scanTokens(s.tokenize(text))
+}
+
+//
+// Same as scan, for placeholders already found by tokenize
+func (s *placeholderScanner) scanTokens(tokens []placeholderToken) ([]string, map[string]bool, error) {
+	result := []string{}
+	optionalReferences := map[string]bool{}
+	parameterNamesDeduped := make(map[string]bool)
+	invalidReferences := []InvalidParameterReference{}
+
+	for _, token := range tokens {
+		found := token.placeholder
+		if token.reason != "" {
+			invalidReferences = append(invalidReferences, InvalidParameterReference{Reference: token.body, Reason: token.reason})
+			continue
+		}
+
+		if optional, seen := optionalReferences[found.reference]; !seen || optional {
+			optionalReferences[found.reference] = found.hasDefault
+		}
+		parameterNamesDeduped[found.reference] = true
+	}
+
+	if len(invalidReferences) > 0 {
+		sort.Slice(invalidReferences, func(i, j int) bool { return invalidReferences[i].Reference < invalidReferences[j].Reference })
+		return nil, nil, &InvalidParameterReferenceError{References: invalidReferences}
+	}
+
+	for key := range parameterNamesDeduped {
+		result = append(result, key)
+		if !optionalReferences[key] {
+			delete(optionalReferences, key)
+		}
+	}
+	return result, optionalReferences, nil
+}
+
+//
+// Replaces every placeholder found in text by tokenize with the filtered value of its reference, or its default
+// when the reference has no value. Other placeholders are kept. Values of placeholders without filters are
+// escaped for the place they appear in when a document format is given. The document is written into one
+// buffer, copying the text between placeholders as is.
+// Returns the first PlaceholderFilterError if a filter fails, or a MalformedDocumentError if a value can't be escaped.
+func (s *placeholderScanner) replaceTokens(
+	text string,
+	tokens []placeholderToken,
+	resolvedParametersMap map[string]SsmParameterInfo,
+	format DocumentFormat) (string, error) {
+
+	if len(tokens) == 0 {
+		return text, nil
+	}
+
+	spans := make([][]int, len(tokens))
+	for i, token := range tokens {
+		spans[i] = []int{token.start, token.end}
+	}
+	contexts := documentContexts(format, text, spans)
+
+	var builder strings.Builder
+	builder.Grow(len(text))
+	last := 0
+	for i, token := range tokens {
+		builder.WriteString(text[last:token.start])
+		last = token.end
+
+		found := token.placeholder
+		if token.reason != "" {
+			builder.WriteString(text[token.start:token.end])
+			continue
+		}
+
+		value := ""
+		if param, ok := resolvedParametersMap[found.reference]; ok {
+			value = param.Value
+		} else if found.hasDefault {
+			value = found.defaultValue
+		} else {
+			builder.WriteString(text[token.start:token.end])
+			continue
+		}
+
+		var err error
+		var filterError *PlaceholderFilterError
+		if len(found.filters) > 0 {
+			if value, err = applyPlaceholderFilters(found.reference, value, found.filters); errors.As(err, &filterError) {
+				filterError.Position = placeholderPositionAt(text, token.start)
+			}
+		} else if value, err = escapeForContext(contexts[i], value); err != nil {
+			err = &MalformedDocumentError{Format: format, Line: lineOfOffset(text, token.start),
+				Err: errors.New("{{" + found.reference + "}}: " + err.Error())}
+		}
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(value)
+	}
+	builder.WriteString(text[last:])
+
+	return builder.String(), nil
+}
+
+//
+// Parses the body of a placeholder, or returns the reason it is malformed
+func (s *placeholderScanner) parsePlaceholder(optional bool, body string) (placeholder, string) {
+	result := placeholder{hasDefault: optional}
+
+	rest := ""
+	if pos := strings.Index(body, placeholderFilterSeparator); pos >= 0 {
+		body, rest = strings.TrimSpace(body[:pos]), body[pos:]
+	}
+
+	hasDefaultValue := false
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		if strings.HasPrefix(rest, placeholderDefaultSeparator) {
+			if hasDefaultValue || len(result.filters) > 0 {
+				return result, "a single default value may only come before the filters"
+			}
+
+			literal := strings.TrimSpace(rest[len(placeholderDefaultSeparator):])
+			if !strings.HasPrefix(literal, "\"") {
+				return result, "default value must be a double-quoted string"
+			}
+			quoted, err := strconv.QuotedPrefix(literal)
+			if err != nil {
+				return result, "malformed default value " + literal
+			}
+			result.defaultValue, _ = strconv.Unquote(quoted)
+			result.hasDefault = true
+			hasDefaultValue = true
+			rest = literal[len(quoted):]
+			continue
+		}
+
+		if !strings.HasPrefix(rest, placeholderFilterSeparator) {
+			return result, "unexpected " + rest + ", expected | filter or || \"default\""
+		}
+		rest = strings.TrimSpace(rest[len(placeholderFilterSeparator):])
+		name := rest
+		if pos := strings.IndexAny(rest, " \t\r\n"+placeholderFilterSeparator); pos >= 0 {
+			name = rest[:pos]
+		}
+		if _, found := lookupPlaceholderFilter(name); !found {
+			return result, "unknown filter " + name
+		}
+		result.filters = append(result.filters, name)
+		rest = rest[len(name):]
+	}
+
+	ref, reason := referenceFromPlaceholder(body)
+	if reason != "" {
+		return result, reason
+	}
+	result.reference = ref
+
+	if result.hasDefault && !s.allowSecureDefaults {
+		if prefix, found := findReferencePrefix(s.prefixes, ref); found && prefix.Secure {
+			return result, "defaults on secure references require ResolveOptions.AllowSecureDefaults"
+		}
+	}
+
+	return result, ""
+}
+
+//
+// Returns the reference written in a placeholder, or the reason it is malformed
+func referenceFromPlaceholder(body string) (string, string) {
+	if strings.HasPrefix(body, cloudFormationResolvePrefix) {
+		return referenceFromCloudFormation(strings.TrimPrefix(body, cloudFormationResolvePrefix))
+	}
+	return body, ""
+}
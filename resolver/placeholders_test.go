@@ -0,0 +1,112 @@
+package resolver
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Regular expression the scanner used before the tokenizer, for ssm: and ssm-secure: with CloudFormation syntax
+var placeholderPattern = regexp.MustCompile(`{{\s*(\?)?\s*((?:ssm:|ssm-secure:|resolve:(?:ssm:|ssm-secure:))[^{}]*?)\s*}}`)
+
+func TestTokenizeMatchesPlaceholderPattern(t *testing.T) {
+	scanner := newPlaceholderScanner(ssmReferencePrefixes, ResolveOptions{CloudFormationSyntax: true})
+	texts := []string{
+		"{{ssm:/a}}",
+		"{{ ? ssm:/a || \"x\" | json }}",
+		"{{{ssm:/a}}}",
+		"{{ssm:/a}",
+		"{{ssm:/a{{ssm:/b}}",
+		"{{ssm:/a} }}{{ssm-secure:/b}}",
+		"{{ resolve:ssm:/a:1 }}{{resolve:secretsmanager:x}}",
+		"{{\tssm:/a \n}} {{?? ssm:/a}} {{ ?\fssm:/a\r}}",
+		"{{ssm-path:/a}} {{ssm:}} {{}} {{ ssm :/a}} }}{{",
+		"{{ssm:/a}}{{ssm:/b}}x{{ssm:/c}}",
+		"",
+	}
+
+	for _, text := range texts {
+		expected := [][]int{}
+		for _, match := range placeholderPattern.FindAllStringSubmatchIndex(text, -1) {
+			expected = append(expected, []int{match[0], match[1], match[4], match[5]})
+		}
+
+		actual := [][]int{}
+		for _, token := range scanner.tokenize(text) {
+			bodyStart := strings.Index(text[token.start:], token.body) + token.start
+			actual = append(actual, []int{token.start, token.end, bodyStart, bodyStart + len(token.body)})
+		}
+		assert.Equal(t, expected, actual, text)
+	}
+}
+
+func TestTokenizeRecordsPlaceholders(t *testing.T) {
+	scanner := newPlaceholderScanner(ssmReferencePrefixes, ResolveOptions{})
+
+	tokens := scanner.tokenize(`a {{?ssm:/x}} b {{ ssm:/y || "d" | base64 }} {{ssm:bad name}}`)
+
+	assert.Equal(t, 3, len(tokens))
+	assert.Equal(t, placeholderToken{start: 2, end: 13, body: "ssm:/x", placeholder: placeholder{reference: "ssm:/x", hasDefault: true}}, tokens[0])
+	assert.Equal(t, placeholder{reference: "ssm:/y", hasDefault: true, defaultValue: "d", filters: []string{"base64"}}, tokens[1].placeholder)
+	assert.Equal(t, "", tokens[2].reason)
+	assert.Equal(t, "ssm:bad name", tokens[2].placeholder.reference)
+
+	assert.Equal(t, []placeholderToken{}, newPlaceholderScanner(nil, ResolveOptions{}).tokenize("{{ssm:/x}}"))
+}
+
+// Document with the given number of placeholders over uniqueReferences parameters
+func newBenchmarkDocument(placeholders int, uniqueReferences int) (string, ServiceMockedObjectWithRecords) {
+	records := map[string]SsmParameterInfo{}
+	for i := 0; i < uniqueReferences; i++ {
+		name := "/bench/param" + strconv.Itoa(i)
+		records["ssm:"+name] = SsmParameterInfo{Name: name, Type: stringType, Value: "value." + strconv.Itoa(i) + "+(x)"}
+	}
+
+	var document strings.Builder
+	for i := 0; i < placeholders; i++ {
+		document.WriteString("key" + strconv.Itoa(i) + " = {{ssm:/bench/param" + strconv.Itoa(i%uniqueReferences) + "}}\n")
+	}
+	return document.String(), NewServiceMockedObjectWithExtraRecords(records)
+}
+
+func benchmarkResolveParametersInText(b *testing.B, placeholders int, uniqueReferences int, options ResolveOptions) {
+	document, serviceObject := newBenchmarkDocument(placeholders, uniqueReferences)
+	b.SetBytes(int64(len(document)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ResolveParametersInText(&serviceObject, document, options); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResolveParametersInText1000Placeholders(b *testing.B) {
+	benchmarkResolveParametersInText(b, 1000, 100, ResolveOptions{})
+}
+
+func BenchmarkResolveParametersInText10000Placeholders(b *testing.B) {
+	benchmarkResolveParametersInText(b, 10000, 1000, ResolveOptions{})
+}
+
+func BenchmarkResolveParametersInText10000UniqueReferences(b *testing.B) {
+	benchmarkResolveParametersInText(b, 10000, 10000, ResolveOptions{MaxConcurrentRequests: 8})
+}
+
+func BenchmarkResolveParametersInTextProperties(b *testing.B) {
+	benchmarkResolveParametersInText(b, 10000, 1000, ResolveOptions{DocumentFormat: DocumentFormatProperties})
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	document, _ := newBenchmarkDocument(10000, 1000)
+	scanner := newPlaceholderScanner(ssmReferencePrefixes, ResolveOptions{})
+	b.SetBytes(int64(len(document)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		scanner.tokenize(document)
+	}
+}
@@ -0,0 +1,234 @@
+package resolver
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//
+// Where a placeholder appears in a document
+type PlaceholderPosition struct {
+	// input file, empty for text
+	FileName string
+	// byte offset of the opening braces
+	Offset int
+	// 1-based line
+	Line int
+	// 1-based column, counted in characters
+	Column int
+}
+
+//
+// Returns the position as file:line:column, or line:column for text
+func (p PlaceholderPosition) String() string {
+	position := strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+	if p.FileName == "" {
+		return position
+	}
+	return p.FileName + ":" + position
+}
+
+//
+// Returns p, a position in a part of a document, as a position in the whole document given the position
+// the part starts at
+func (p PlaceholderPosition) within(origin PlaceholderPosition) PlaceholderPosition {
+	if p.Line == 1 {
+		p.Column += origin.Column - 1
+	}
+	p.Line += origin.Line - 1
+	p.Offset += origin.Offset
+	return p
+}
+
+//
+// Returns the position right after text, which starts at origin
+func positionAfter(origin PlaceholderPosition, text string) PlaceholderPosition {
+	origin.Offset += len(text)
+	if lastLineStart := strings.LastIndexByte(text, '\n') + 1; lastLineStart > 0 {
+		origin.Line += strings.Count(text, "\n")
+		origin.Column = 1
+		text = text[lastLineStart:]
+	}
+	origin.Column += utf8.RuneCountInString(text)
+	return origin
+}
+
+//
+// A placeholder found in a document
+type PlaceholderOccurrence struct {
+	// reference the placeholder stands for, CloudFormation dynamic references are translated.
+	// The text between the braces when the placeholder is malformed.
+	Reference string
+	// the placeholder as written, braces included
+	Raw      string
+	Position PlaceholderPosition
+	// why the placeholder or its reference is invalid, empty if it is valid
+	InvalidReason string
+}
+
+//
+// Returns every placeholder in input in the order they appear, with their positions. Unlike
+// ExtractParametersFromText nothing is resolved; malformed placeholders and references that break
+// the naming rules of their store are returned with the reason.
+func ExtractPlaceholdersFromText(
+	service IParameterProvider,
+	input string,
+	options ResolveOptions) []PlaceholderOccurrence {
+
+	return extractPlaceholders(service, input, "", options)
+}
+
+//
+// Same as ExtractPlaceholdersFromText for the content of a file, positions carry the file name.
+func ExtractPlaceholdersFromFile(
+	service IParameterProvider,
+	inputFileName string,
+	options ResolveOptions) ([]PlaceholderOccurrence, error) {
+
+	if len(inputFileName) == 0 {
+		return nil, errors.New("input file name is not provided")
+	}
+
+	if err := validateFileAndSize(inputFileName); err != nil {
+		return nil, err
+	}
+
+	text, err := readTextFromFile(inputFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractPlaceholders(service, text, inputFileName, options), nil
+}
+
+func extractPlaceholders(service IParameterProvider, text string, fileName string, options ResolveOptions) []PlaceholderOccurrence {
+	prefixes := referencePrefixesOf(service)
+	tokens := newPlaceholderScanner(prefixes, options).tokenize(text)
+	positions := placeholderPositions(text, tokens, fileName)
+
+	occurrences := []PlaceholderOccurrence{}
+	for i, token := range tokens {
+		occurrence := PlaceholderOccurrence{
+			Reference:     token.placeholder.reference,
+			Raw:           text[token.start:token.end],
+			Position:      positions[i],
+			InvalidReason: token.reason,
+		}
+
+		if token.reason != "" {
+			occurrence.Reference = token.body
+		} else {
+			var invalidReferenceError *InvalidParameterReferenceError
+			if errors.As(validateParameterReferences([]string{occurrence.Reference}, prefixes), &invalidReferenceError) {
+				occurrence.InvalidReason = invalidReferenceError.References[0].Reason
+			}
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
+
+//
+// Returns the positions of tokens, which are ordered by offset, in one pass over text
+func placeholderPositions(text string, tokens []placeholderToken, fileName string) []PlaceholderPosition {
+	positions := make([]PlaceholderPosition, len(tokens))
+	line, lineStart, scanned := 1, 0, 0
+	for i, token := range tokens {
+		for {
+			next := strings.IndexByte(text[scanned:token.start], '\n')
+			if next < 0 {
+				break
+			}
+			line++
+			lineStart = scanned + next + 1
+			scanned = lineStart
+		}
+		scanned = token.start
+
+		positions[i] = PlaceholderPosition{
+			FileName: fileName,
+			Offset:   token.start,
+			Line:     line,
+			Column:   utf8.RuneCountInString(text[lineStart:token.start]) + 1,
+		}
+	}
+	return positions
+}
+
+//
+// Returns the positions of the placeholders of every reference. Malformed placeholders are listed
+// under the text between their braces, which is what InvalidParameterReferenceError reports for them.
+func placeholderPositionsByReference(text string, tokens []placeholderToken, fileName string) map[string][]PlaceholderPosition {
+	result := map[string][]PlaceholderPosition{}
+	for i, position := range placeholderPositions(text, tokens, fileName) {
+		reference := tokens[i].placeholder.reference
+		if tokens[i].reason != "" {
+			reference = tokens[i].body
+		}
+		result[reference] = append(result[reference], position)
+	}
+	return result
+}
+
+//
+// Adds the positions of the placeholders in text to the references listed by a resolution error
+func withPlaceholderPositions(err error, text string, tokens []placeholderToken, fileName string) error {
+	var filterError *PlaceholderFilterError
+	if errors.As(err, &filterError) {
+		filterError.Position.FileName = fileName
+		return err
+	}
+	return withReferencePositions(err, placeholderPositionsByReference(text, tokens, fileName))
+}
+
+//
+// Adds the positions of their references to the references listed by a resolution error
+func withReferencePositions(err error, positions map[string][]PlaceholderPosition) error {
+	var invalidReferenceError *InvalidParameterReferenceError
+	var resolutionError *ParameterResolutionError
+
+	switch {
+	case errors.As(err, &invalidReferenceError):
+		invalidReferenceError.Positions = map[string][]PlaceholderPosition{}
+		for _, ref := range invalidReferenceError.References {
+			if found, ok := positions[ref.Reference]; ok {
+				invalidReferenceError.Positions[ref.Reference] = found
+			}
+		}
+
+	case errors.As(err, &resolutionError):
+		resolutionError.Positions = map[string][]PlaceholderPosition{}
+		references := append([]string{}, resolutionError.MissingReferences...)
+		for _, mismatch := range resolutionError.TypeMismatches {
+			references = append(references, mismatch.Reference)
+		}
+		for _, ref := range references {
+			if found, ok := positions[ref]; ok {
+				resolutionError.Positions[ref] = found
+			}
+		}
+	}
+	return err
+}
+
+//
+// Returns the position of the placeholder at offset in text
+func placeholderPositionAt(text string, offset int) PlaceholderPosition {
+	return placeholderPositions(text, []placeholderToken{{start: offset}}, "")[0]
+}
+
+//
+// Formats positions for error messages, e.g. 3:5, 9:1
+func positionsDescription(positions []PlaceholderPosition) string {
+	sorted := append([]PlaceholderPosition{}, positions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+
+	descriptions := []string{}
+	for _, position := range sorted {
+		descriptions = append(descriptions, position.String())
+	}
+	return strings.Join(descriptions, ", ")
+}
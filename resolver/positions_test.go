@@ -0,0 +1,89 @@
+package resolver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPositionsServiceMock() ServiceMockedObjectWithRecords {
+	return NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/name":        {Name: "/app/name", Type: stringType, Value: "demo"},
+		"ssm-secure:/app/name": {Name: "/app/name", Type: stringType, Value: "demo"},
+	})
+}
+
+func TestExtractPlaceholdersFromText(t *testing.T) {
+	serviceObject := newPositionsServiceMock()
+	text := "name: {{ssm:/app/name}}\n" +
+		"é: {{ ?ssm:/app/other || \"x\" }} {{ssm:bad name}}\n" +
+		"{{resolve:ssm:/app/name:1}} {{ssm:/app/name | nosuchfilter}}"
+
+	occurrences := ExtractPlaceholdersFromText(&serviceObject, text, ResolveOptions{CloudFormationSyntax: true})
+
+	assert.Equal(t, []PlaceholderOccurrence{
+		{Reference: "ssm:/app/name", Raw: "{{ssm:/app/name}}", Position: PlaceholderPosition{Offset: 6, Line: 1, Column: 7}},
+		{Reference: "ssm:/app/other", Raw: `{{ ?ssm:/app/other || "x" }}`, Position: PlaceholderPosition{Offset: 28, Line: 2, Column: 4}},
+		{Reference: "ssm:bad name", Raw: "{{ssm:bad name}}", Position: PlaceholderPosition{Offset: 57, Line: 2, Column: 33},
+			InvalidReason: "name may only contain letters, numbers and the symbols . - _ /"},
+		{Reference: "ssm:/app/name:1", Raw: "{{resolve:ssm:/app/name:1}}", Position: PlaceholderPosition{Offset: 74, Line: 3, Column: 1}},
+		{Reference: "ssm:/app/name | nosuchfilter", Raw: "{{ssm:/app/name | nosuchfilter}}", Position: PlaceholderPosition{Offset: 102, Line: 3, Column: 29},
+			InvalidReason: "unknown filter nosuchfilter"},
+	}, occurrences)
+}
+
+func TestExtractPlaceholdersFromFile(t *testing.T) {
+	serviceObject := newPositionsServiceMock()
+	inputFileName := filepath.Join(t.TempDir(), "config.txt")
+	assert.Nil(t, os.WriteFile(inputFileName, []byte("a\n  {{ssm:/app/name}}"), 0600))
+
+	occurrences, err := ExtractPlaceholdersFromFile(&serviceObject, inputFileName, ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(occurrences))
+	assert.Equal(t, PlaceholderPosition{FileName: inputFileName, Offset: 4, Line: 2, Column: 3}, occurrences[0].Position)
+	assert.Equal(t, inputFileName+":2:3", occurrences[0].Position.String())
+
+	_, err = ExtractPlaceholdersFromFile(&serviceObject, filepath.Join(t.TempDir(), "missing.txt"), ResolveOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestResolutionErrorsCarryPositions(t *testing.T) {
+	serviceObject := newPositionsServiceMock()
+
+	_, err := ResolveParametersInText(&serviceObject, "{{ssm:/app/missing}}\n x {{ssm:/app/missing}} {{ssm-secure:/app/name}}", ResolveOptions{})
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, []PlaceholderPosition{{Offset: 0, Line: 1, Column: 1}, {Offset: 24, Line: 2, Column: 4}},
+		resolutionError.Positions["ssm:/app/missing"])
+	assert.Equal(t, []PlaceholderPosition{{Offset: 45, Line: 2, Column: 25}}, resolutionError.Positions["ssm-secure:/app/name"])
+	assert.Contains(t, err.Error(), "ssm:/app/missing (at 1:1, 2:4)")
+	assert.Contains(t, err.Error(), "type String (at 2:25)")
+
+	_, err = ExtractParametersFromText(&serviceObject, "\n\n  {{ssm:bad name}}", ResolveOptions{})
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+	assert.Equal(t, []PlaceholderPosition{{Offset: 4, Line: 3, Column: 3}}, invalidReferenceError.Positions["ssm:bad name"])
+	assert.Contains(t, err.Error(), "{{ssm:bad name}} at 3:3 (")
+
+	_, err = ResolveParametersInText(&serviceObject, "x\n{{ssm:/app/none || \"not base64!\" | base64decode}}", ResolveOptions{})
+	var filterError *PlaceholderFilterError
+	assert.True(t, errors.As(err, &filterError))
+	assert.Equal(t, PlaceholderPosition{Offset: 2, Line: 2, Column: 1}, filterError.Position)
+}
+
+func TestResolveParametersInFileErrorsCarryFileName(t *testing.T) {
+	serviceObject := newPositionsServiceMock()
+	directory := t.TempDir()
+	inputFileName := filepath.Join(directory, "config.txt")
+	assert.Nil(t, os.WriteFile(inputFileName, []byte("value={{ssm:/app/missing}}"), 0600))
+
+	err := ResolveParametersInFile(&serviceObject, inputFileName, filepath.Join(directory, "out.txt"), ResolveOptions{})
+
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Contains(t, err.Error(), "ssm:/app/missing (at "+inputFileName+":1:7)")
+}
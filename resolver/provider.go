@@ -0,0 +1,155 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+//
+// IParameterProvider is implemented by every backend the resolver can fetch parameters from.
+// GetParameters takes a batch of parameter references (e.g. ssm:name) and returns
+// a map of (parameter reference) to SsmParameterInfo.
+type IParameterProvider interface {
+	GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error)
+}
+
+//
+// ISsmParameterService is the former name of IParameterProvider, kept for existing callers.
+type ISsmParameterService = IParameterProvider
+
+//
+// ParameterProviderFunc adapts an ordinary function to the IParameterProvider interface.
+type ParameterProviderFunc func(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error)
+
+func (f ParameterProviderFunc) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	return f(ctx, parameterReferences)
+}
+
+//
+// ReferencePrefix describes a reference prefix like ssm: and whether values behind it are secrets,
+// which are skipped when ResolveOptions.IgnoreSecureParameters is set.
+type ReferencePrefix struct {
+	Prefix string
+	Secure bool
+}
+
+//
+// Prefixes of a plain SSM provider
+var ssmReferencePrefixes = []ReferencePrefix{
+	{Prefix: ssmNonSecurePrefix, Secure: false},
+	{Prefix: ssmSecurePrefix, Secure: true},
+}
+
+//
+// Prefixes of Service, which also lists parameters by path and renders StringList parameters.
+// Listings may contain decrypted SecureString values, so path references are secure.
+var ssmServiceReferencePrefixes = append(append([]ReferencePrefix{}, ssmReferencePrefixes...),
+	ReferencePrefix{Prefix: ssmPathPrefix, Secure: true},
+	ReferencePrefix{Prefix: ssmListPrefix, Secure: false})
+
+//
+// IPrefixedParameterProvider is implemented by providers that serve other prefixes than ssm: and ssm-secure:.
+// Placeholders are only recognized for the prefixes the provider passed to the resolve functions supports.
+type IPrefixedParameterProvider interface {
+	IParameterProvider
+	ReferencePrefixes() []ReferencePrefix
+}
+
+//
+// Returns the prefixes served by provider, ssm: and ssm-secure: unless it tells otherwise
+func referencePrefixesOf(provider IParameterProvider) []ReferencePrefix {
+	if prefixedProvider, ok := provider.(IPrefixedParameterProvider); ok {
+		return prefixedProvider.ReferencePrefixes()
+	}
+	return ssmReferencePrefixes
+}
+
+//
+// Returns the prefix of a reference including the colon, e.g. ssm: for ssm:/a/b
+func referencePrefix(parameterReference string) string {
+	return parameterReference[:strings.Index(parameterReference, ":")+1]
+}
+
+//
+// ProviderRouter is an IParameterProvider that sends every reference to the provider registered
+// for its prefix, so references to different stores can be mixed in one document.
+type ProviderRouter struct {
+	prefixes  []ReferencePrefix
+	providers []IParameterProvider
+	// index in providers by prefix; providers may not be comparable (e.g. ParameterProviderFunc), so they aren't map keys
+	providerIndexByPrefix map[string]int
+}
+
+var _ IPrefixedParameterProvider = (*ProviderRouter)(nil)
+
+func NewProviderRouter() *ProviderRouter {
+	return &ProviderRouter{providerIndexByPrefix: map[string]int{}}
+}
+
+//
+// Registers provider for the given prefixes. Without prefixes the ones the provider reports itself are used,
+// which are ssm: and ssm-secure: for providers that don't implement IPrefixedParameterProvider.
+// Registering a prefix again replaces its provider.
+func (r *ProviderRouter) Register(provider IParameterProvider, prefixes ...ReferencePrefix) *ProviderRouter {
+	if len(prefixes) == 0 {
+		prefixes = referencePrefixesOf(provider)
+	}
+
+	r.providers = append(r.providers, provider)
+	for _, prefix := range prefixes {
+		if _, found := r.providerIndexByPrefix[prefix.Prefix]; !found {
+			r.prefixes = append(r.prefixes, prefix)
+		} else {
+			for i := range r.prefixes {
+				if r.prefixes[i].Prefix == prefix.Prefix {
+					r.prefixes[i] = prefix
+				}
+			}
+		}
+		r.providerIndexByPrefix[prefix.Prefix] = len(r.providers) - 1
+	}
+	return r
+}
+
+func (r *ProviderRouter) ReferencePrefixes() []ReferencePrefix {
+	return append([]ReferencePrefix{}, r.prefixes...)
+}
+
+func (r *ProviderRouter) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	providerOrder := []int{}
+	referencesByProvider := map[int][]string{}
+
+	for _, ref := range parameterReferences {
+		provider, found := r.providerIndexByPrefix[referencePrefix(ref)]
+		if !found {
+			return nil, errors.New("no parameter provider is registered for {{" + ref + "}}")
+		}
+		if _, seen := referencesByProvider[provider]; !seen {
+			providerOrder = append(providerOrder, provider)
+		}
+		referencesByProvider[provider] = append(referencesByProvider[provider], ref)
+	}
+
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+	for _, provider := range providerOrder {
+		parameters, err := r.providers[provider].GetParameters(ctx, referencesByProvider[provider])
+
+		var missingParametersError *MissingParametersError
+		if errors.As(err, &missingParametersError) {
+			missingReferences = append(missingReferences, missingParametersError.References...)
+		} else if err != nil {
+			return nil, err
+		}
+
+		for ref, value := range parameters {
+			result[ref] = value
+		}
+	}
+
+	if len(missingReferences) > 0 {
+		return result, newMissingParametersError(missingReferences)
+	}
+	return result, nil
+}
@@ -0,0 +1,207 @@
+package resolver
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const arnPrefix = "arn:"
+
+//
+// SSM naming rules, see https://docs.aws.amazon.com/systems-manager/latest/userguide/sysman-parameter-name-constraints.html
+const maxParameterNameLength = 1011
+const maxParameterHierarchyLevels = 15
+const maxParameterLabelLength = 100
+
+var parameterNameCharacters = regexp.MustCompile(`^[a-zA-Z0-9_.\-/]+$`)
+var parameterArn = regexp.MustCompile(`^arn:aws[a-z-]*:ssm:[a-z0-9-]+:\d{12}:parameter(/.*)$`)
+var parameterLabelCharacters = regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`)
+
+//
+// Number of colon separated fields before the resource part of an ARN (arn:partition:service:region:account:)
+const arnFieldsBeforeResource = 5
+
+//
+// Parsed form of a parameter reference like ssm:/a/b/c:3
+type parameterReference struct {
+	// prefix including the trailing colon, e.g. ssm: or ssm-secure:
+	prefix string
+	// parameter name or full ARN, without prefix and selector
+	name string
+	// version number or label, empty when the latest version is requested
+	selector string
+}
+
+func parseParameterReference(reference string) parameterReference {
+	result := parameterReference{}
+
+	if pos := strings.Index(reference, ":"); pos >= 0 {
+		result.prefix = reference[:pos+1]
+		reference = reference[pos+1:]
+	}
+
+	// ARNs contain colons of their own, the selector may only follow the resource part
+	resourceStart := 0
+	if strings.HasPrefix(reference, arnPrefix) {
+		resourceStart = arnResourceStart(reference)
+	}
+
+	if pos := strings.LastIndex(reference[resourceStart:], ":"); pos >= 0 {
+		result.selector = reference[resourceStart+pos+1:]
+		reference = reference[:resourceStart+pos]
+	}
+
+	result.name = reference
+	return result
+}
+
+//
+// Returns the offset of the resource part (e.g. parameter/a/b) in an ARN
+func arnResourceStart(arn string) int {
+	pos := 0
+	for i := 0; i < arnFieldsBeforeResource; i++ {
+		next := strings.Index(arn[pos:], ":")
+		if next < 0 {
+			return len(arn)
+		}
+		pos += next + 1
+	}
+	return pos
+}
+
+//
+// Returns the name in the form accepted by SSM GetParameters, i.e. name or name:selector
+func (r parameterReference) nameWithSelector() string {
+	if r.selector == "" {
+		return r.name
+	}
+	return r.name + ":" + r.selector
+}
+
+//
+// Checks the reference against the SSM naming rules and returns the reason it is invalid, or an empty string.
+// The aws and ssm name prefixes are reserved for creating parameters only, public parameters like
+// /aws/service/... can still be read, so they are not rejected here.
+func (r parameterReference) validate() string {
+	if len(r.name) > maxParameterNameLength {
+		return "name is longer than " + strconv.Itoa(maxParameterNameLength) + " characters"
+	}
+
+	name := r.name
+	if strings.HasPrefix(name, arnPrefix) {
+		match := parameterArn.FindStringSubmatch(name)
+		if match == nil {
+			return "malformed parameter ARN"
+		}
+		name = match[1]
+	}
+
+	if reason := validateParameterName(name); reason != "" {
+		return reason
+	}
+
+	if r.selector != "" {
+		return validateParameterSelector(r.selector)
+	}
+
+	return ""
+}
+
+func validateParameterName(name string) string {
+	if name == "" {
+		return "name is empty"
+	}
+
+	if !parameterNameCharacters.MatchString(name) {
+		return "name may only contain letters, numbers and the symbols . - _ /"
+	}
+
+	if strings.Contains(name, "/") {
+		if !strings.HasPrefix(name, "/") {
+			return "hierarchical name must begin with /"
+		}
+
+		levels := strings.Split(name[1:], "/")
+		if len(levels) > maxParameterHierarchyLevels {
+			return "hierarchy is deeper than " + strconv.Itoa(maxParameterHierarchyLevels) + " levels"
+		}
+
+		for _, level := range levels {
+			if level == "" {
+				return "hierarchy contains an empty level"
+			}
+		}
+	}
+
+	return ""
+}
+
+//
+// Selector is either a version number or a label. Labels can't begin with a number or with aws/ssm.
+func validateParameterSelector(selector string) string {
+	if selector[0] >= '0' && selector[0] <= '9' {
+		if version, err := strconv.ParseInt(selector, 10, 64); err != nil || version < 1 {
+			return "version must be a positive number"
+		}
+		return ""
+	}
+
+	if len(selector) > maxParameterLabelLength {
+		return "label is longer than " + strconv.Itoa(maxParameterLabelLength) + " characters"
+	}
+
+	if !parameterLabelCharacters.MatchString(selector) {
+		return "label may only contain letters, numbers and the symbols . - _"
+	}
+
+	lowerCaseSelector := strings.ToLower(selector)
+	if strings.HasPrefix(lowerCaseSelector, "aws") || strings.HasPrefix(lowerCaseSelector, "ssm") {
+		return "label can't begin with aws or ssm"
+	}
+
+	return ""
+}
+
+//
+// Returns an InvalidParameterReferenceError for the references with prefixes not in the list, and for
+// SSM references that break the SSM naming rules, or nil. References to other stores are checked by their providers.
+func validateParameterReferences(parameterReferences []string, prefixes []ReferencePrefix) error {
+	invalidReferences := []InvalidParameterReference{}
+	for _, ref := range parameterReferences {
+		reason := ""
+		if prefix, found := findReferencePrefix(prefixes, ref); !found {
+			reason = "unknown prefix " + referencePrefix(ref)
+		} else if prefix.Prefix == ssmNonSecurePrefix || prefix.Prefix == ssmSecurePrefix {
+			reason = parseParameterReference(ref).validate()
+		} else if prefix.Prefix == ssmPathPrefix {
+			_, reason = parseParameterPathReference(ref)
+		} else if prefix.Prefix == ssmListPrefix {
+			_, reason = parseParameterListReference(ref)
+		}
+
+		if reason != "" {
+			invalidReferences = append(invalidReferences, InvalidParameterReference{Reference: ref, Reason: reason})
+		}
+	}
+
+	if len(invalidReferences) == 0 {
+		return nil
+	}
+
+	sort.Slice(invalidReferences, func(i, j int) bool {
+		return invalidReferences[i].Reference < invalidReferences[j].Reference
+	})
+	return &InvalidParameterReferenceError{References: invalidReferences}
+}
+
+func findReferencePrefix(prefixes []ReferencePrefix, parameterReference string) (ReferencePrefix, bool) {
+	prefix := referencePrefix(parameterReference)
+	for _, candidate := range prefixes {
+		if candidate.Prefix == prefix {
+			return candidate, true
+		}
+	}
+	return ReferencePrefix{}, false
+}
@@ -0,0 +1,94 @@
+package resolver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseParameterReference(t *testing.T) {
+	testCases := map[string]parameterReference{
+		"ssm:param":            {prefix: ssmNonSecurePrefix, name: "param"},
+		"ssm:/a/b/c:3":         {prefix: ssmNonSecurePrefix, name: "/a/b/c", selector: "3"},
+		"ssm-secure:/a/b:prod": {prefix: ssmSecurePrefix, name: "/a/b", selector: "prod"},
+		"ssm:arn:aws:ssm:us-east-1:123456789012:parameter/a/b": {prefix: ssmNonSecurePrefix,
+			name: "arn:aws:ssm:us-east-1:123456789012:parameter/a/b"},
+		"ssm:arn:aws-us-gov:ssm:us-gov-west-1:123456789012:parameter/x:7": {prefix: ssmNonSecurePrefix,
+			name: "arn:aws-us-gov:ssm:us-gov-west-1:123456789012:parameter/x", selector: "7"},
+	}
+
+	for reference, expected := range testCases {
+		assert.Equal(t, expected, parseParameterReference(reference), reference)
+	}
+}
+
+func TestParseParametersFromTextWithArn(t *testing.T) {
+	text := "{{ssm:arn:aws:ssm:us-east-1:123456789012:parameter/a/b}} {{ ssm-secure:arn:aws:ssm:eu-west-1:123456789012:parameter/x:prod }}"
+
+	list, err := scanReferences(text, ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{
+		"ssm:arn:aws:ssm:us-east-1:123456789012:parameter/a/b",
+		"ssm-secure:arn:aws:ssm:eu-west-1:123456789012:parameter/x:prod",
+	}, list)
+}
+
+func TestParameterReferenceValidate(t *testing.T) {
+	validReferences := []string{
+		"ssm:param",
+		"ssm:/app/db.host",
+		"ssm:/app/db_host-1",
+		"ssm:/aws/service/ami-amazon-linux-latest/amzn2-ami-hvm-x86_64-gp2",
+		"ssm:/a/b:12",
+		"ssm-secure:/a/b:prod.v1",
+		"ssm:arn:aws:ssm:us-east-1:123456789012:parameter/app/db.host:3",
+		"ssm:/" + strings.Repeat("l/", maxParameterHierarchyLevels-1) + "l",
+	}
+	for _, ref := range validReferences {
+		assert.Equal(t, "", parseParameterReference(ref).validate(), ref)
+	}
+
+	invalidReferences := []string{
+		"ssm:",
+		"ssm:/app/db host",
+		"ssm:app/db",
+		"ssm:/app//db",
+		"ssm:/app/db/",
+		"ssm:/a/b:0",
+		"ssm:/a/b:1abc",
+		"ssm:/a/b:awsLabel",
+		"ssm:/a/b:" + strings.Repeat("l", maxParameterLabelLength+1),
+		"ssm:/" + strings.Repeat("l/", maxParameterHierarchyLevels) + "l",
+		"ssm:/" + strings.Repeat("n", maxParameterNameLength),
+		"ssm:arn:aws:s3:::bucket/key",
+	}
+	for _, ref := range invalidReferences {
+		assert.NotEqual(t, "", parseParameterReference(ref).validate(), ref)
+	}
+}
+
+func TestParseParametersFromTextReportsInvalidNames(t *testing.T) {
+	text := "{{ssm:/app/db.host}} {{ ssm:/app/db host }} {{ssm-secure:app/password}}"
+
+	_, err := scanReferences(text, ResolveOptions{})
+
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+	assert.Equal(t, 2, len(invalidReferenceError.References))
+	assert.Equal(t, "ssm-secure:app/password", invalidReferenceError.References[0].Reference)
+	assert.Equal(t, "ssm:/app/db host", invalidReferenceError.References[1].Reference)
+}
+
+func TestValidateParameterReferencesUnknownPrefix(t *testing.T) {
+	err := validateParameterReferences([]string{"ssm:param", "unknown:param", "secretsmanager:secret"}, ssmReferencePrefixes)
+
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+	assert.Equal(t, []InvalidParameterReference{
+		{Reference: "secretsmanager:secret", Reason: "unknown prefix secretsmanager:"},
+		{Reference: "unknown:param", Reason: "unknown prefix unknown:"},
+	}, invalidReferenceError.References)
+}
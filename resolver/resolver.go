@@ -1,51 +1,67 @@
 package resolver
 
 import (
+	"context"
 	"errors"
-	"regexp"
-	"strings"
+	"sort"
 )
 
 //
 // Takes text document and resolves all parameters in it according to ResolveOptions.
 // It will return a map of (parameter reference) to SsmParameterInfo.
 func ExtractParametersFromText(
-	service ISsmParameterService,
+	service IParameterProvider,
 	input string,
 	options ResolveOptions) (map[string]SsmParameterInfo, error) {
 
-	uniqueParameterReferences, err := parseParametersFromTextIntoDedupedSlice(input, options.IgnoreSecureParameters)
-	if err != nil {
-		return nil, err
-	}
+	return ExtractParametersFromTextWithContext(context.Background(), service, input, options)
+}
 
-	parametersWithValues, err := getParametersFromSsmParameterStore(service, uniqueParameterReferences)
-	if err != nil {
-		return nil, err
-	}
+//
+// Same as ExtractParametersFromText, but SSM requests are bound to ctx:
+// cancelling it or hitting its deadline stops all pending batches.
+func ExtractParametersFromTextWithContext(
+	ctx context.Context,
+	service IParameterProvider,
+	input string,
+	options ResolveOptions) (map[string]SsmParameterInfo, error) {
 
-	prefixValidationError := validateParameterReferencePrefix(&parametersWithValues)
-	if prefixValidationError != nil {
-		return nil, prefixValidationError
-	}
+	scanner := newPlaceholderScanner(referencePrefixesOf(service), options)
+	tokens := scanner.tokenize(input)
 
-	return parametersWithValues, nil
+	resolvedParametersMap, err := resolveTokens(ctx, service, scanner, tokens, options)
+	if err != nil {
+		return nil, withPlaceholderPositions(err, input, tokens, "")
+	}
+	return resolvedParametersMap, nil
 }
 
 //
 // Takes a list of references to SSM parameters, resolves them according to ResolveOptions and
 // returns a map of (parameter reference) to SsmParameterInfo.
 func ResolveParameterReferenceList(
-	service ISsmParameterService,
+	service IParameterProvider,
+	parameterReferences []string,
+	options ResolveOptions) (map[string]SsmParameterInfo, error) {
+
+	return ResolveParameterReferenceListWithContext(context.Background(), service, parameterReferences, options)
+}
+
+//
+// Same as ResolveParameterReferenceList, but SSM requests are bound to ctx.
+func ResolveParameterReferenceListWithContext(
+	ctx context.Context,
+	service IParameterProvider,
 	parameterReferences []string,
 	options ResolveOptions) (map[string]SsmParameterInfo, error) {
 
 	uniqueParameterReferences := dedupSlice(parameterReferences)
+	prefixes := referencePrefixesOf(service)
 
 	parameterReferencesToResolve := []string{}
 	if options.IgnoreSecureParameters {
 		for _, ref := range uniqueParameterReferences {
-			if strings.HasPrefix(ref, ssmNonSecurePrefix) {
+			if prefix, found := findReferencePrefix(prefixes, ref); !found || !prefix.Secure {
 				parameterReferencesToResolve = append(parameterReferencesToResolve, ref)
 			}
 		}
@@ -53,45 +69,56 @@ func ResolveParameterReferenceList(
 		parameterReferencesToResolve = append(parameterReferencesToResolve, uniqueParameterReferences...)
 	}
 
-	parametersWithValues, err := getParametersFromSsmParameterStore(service, parameterReferencesToResolve)
-	if err != nil {
+	if err := validateParameterReferences(parameterReferencesToResolve, prefixes); err != nil {
 		return nil, err
 	}
 
-	prefixValidationError := validateParameterReferencePrefix(&parametersWithValues)
-	if prefixValidationError != nil {
-		return nil, prefixValidationError
-	}
-
-	return parametersWithValues, nil
+	return resolveParameterReferences(ctx, service, parameterReferencesToResolve, nil, options)
 }
 
 //
 // Takes text document, resolves all parameters in it according to ResolveOptions
 // and returns resolved document.
 func ResolveParametersInText(
-	service ISsmParameterService,
+	service IParameterProvider,
 	input string,
 	options ResolveOptions) (string, error) {
 
-	resolvedParametersMap, err := ExtractParametersFromText(service, input, options)
-	if err != nil || resolvedParametersMap == nil || len(resolvedParametersMap) == 0 {
-		return input, err
-	}
+	return ResolveParametersInTextWithContext(context.Background(), service, input, options)
+}
 
-	for ref, param := range resolvedParametersMap {
-		var placeholder = regexp.MustCompile("{{\\s*" + ref + "\\s*}}")
-		input = placeholder.ReplaceAllString(input, param.Value)
-	}
+//
+// Same as ResolveParametersInText, but SSM requests are bound to ctx.
+func ResolveParametersInTextWithContext(
+	ctx context.Context,
+	service IParameterProvider,
+	input string,
+	options ResolveOptions) (string, error) {
 
-	return input, nil
+	resolvedText, err := resolveText(ctx, service, input, options)
+	if err != nil {
+		return input, err
+	}
+	return resolvedText, nil
 }
 
 //
 // Reads inputFileName, resolves SSM parameters in it according to ResolveOptions and
 // stores resolved document in the outputFileName file.
 func ResolveParametersInFile(
-	service ISsmParameterService,
+	service IParameterProvider,
+	inputFileName string,
+	outputFileName string,
+	options ResolveOptions) error {
+
+	return ResolveParametersInFileWithContext(context.Background(), service, inputFileName, outputFileName, options)
+}
+
+//
+// Same as ResolveParametersInFile, but SSM requests are bound to ctx.
+func ResolveParametersInFileWithContext(
+	ctx context.Context,
+	service IParameterProvider,
 	inputFileName string,
 	outputFileName string,
 	options ResolveOptions) error {
@@ -114,17 +141,12 @@ func ResolveParametersInFile(
 		return err
 	}
 
-	resolvedParametersMap, err := ExtractParametersFromText(service, unresolvedText, options)
-	if err != nil || resolvedParametersMap == nil || len(resolvedParametersMap) == 0 {
+	resolvedText, err := resolveText(ctx, service, unresolvedText, options, inputFileName, outputFileName)
+	if err != nil {
 		return err
 	}
 
-	for ref, param := range resolvedParametersMap {
-		var placeholder = regexp.MustCompile("{{\\s*" + ref + "\\s*}}")
-		unresolvedText = placeholder.ReplaceAllString(unresolvedText, param.Value)
-	}
-
-	err = writeToFile(unresolvedText, outputFileName)
+	err = writeToFile(resolvedText, outputFileName)
 	if err != nil {
 		return err
 	}
@@ -132,18 +154,137 @@ func ResolveParametersInFile(
 	return nil
 }
 
-func validateParameterReferencePrefix(resolvedParametersMap *map[string]SsmParameterInfo) error {
+//
+// Resolves the placeholders of text: they are found once, their references are fetched and the values
+// are substituted for the same placeholders in one pass. The file names help to detect the document format,
+// the first one is the input file named in the positions of errors.
+func resolveText(
+	ctx context.Context,
+	service IParameterProvider,
+	text string,
+	options ResolveOptions,
+	fileNames ...string) (string, error) {
+
+	inputFileName := ""
+	if len(fileNames) > 0 {
+		inputFileName = fileNames[0]
+	}
+
+	scanner := newPlaceholderScanner(referencePrefixesOf(service), options)
+	tokens := scanner.tokenize(text)
+
+	resolvedParametersMap, err := resolveTokens(ctx, service, scanner, tokens, options)
+	if err != nil {
+		return "", withPlaceholderPositions(err, text, tokens, inputFileName)
+	}
+
+	format := detectDocumentFormat(options.DocumentFormat, text, fileNames...)
+	resolvedText, err := scanner.replaceTokens(text, tokens, resolvedParametersMap, format)
+	if err != nil {
+		return "", withPlaceholderPositions(err, text, tokens, inputFileName)
+	}
+
+	if err := validateDocument(format, resolvedText); err != nil {
+		return "", err
+	}
+	return resolvedText, nil
+}
+
+//
+// Checks and resolves the references of placeholders found by tokenize
+func resolveTokens(
+	ctx context.Context,
+	service IParameterProvider,
+	scanner *placeholderScanner,
+	tokens []placeholderToken,
+	options ResolveOptions) (map[string]SsmParameterInfo, error) {
+
+	references, optionalReferences, err := scanner.scanTokens(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateParameterReferences(references, scanner.prefixes); err != nil {
+		return nil, err
+	}
+
+	return resolveParameterReferences(ctx, service, references, optionalReferences, options)
+}
+
+//
+// Fetches all references and checks their types. Missing parameters and type mismatches
+// are collected over all batches and reported together as a ParameterResolutionError.
+// Optional references, which have defaults, may be missing; they are left out of the result.
+func resolveParameterReferences(
+	ctx context.Context,
+	service IParameterProvider,
+	parameterReferences []string,
+	optionalReferences map[string]bool,
+	options ResolveOptions) (map[string]SsmParameterInfo, error) {
+
+	parametersWithValues, err := getParametersFromSsmParameterStore(ctx, service, parameterReferences, options.MaxConcurrentRequests)
+
+	var missingParametersError *MissingParametersError
+	if err != nil && !errors.As(err, &missingParametersError) {
+		return nil, err
+	}
+
+	missingReferences := []string{}
+	if missingParametersError != nil {
+		for _, ref := range missingParametersError.References {
+			if !optionalReferences[ref] {
+				missingReferences = append(missingReferences, ref)
+			}
+		}
+	}
+
+	typeMismatches := validateParameterReferencePrefix(&parametersWithValues)
+
+	if len(missingReferences) > 0 || len(typeMismatches) > 0 {
+		resolutionError := &ParameterResolutionError{TypeMismatches: typeMismatches}
+		if len(missingReferences) > 0 {
+			resolutionError.MissingReferences = missingReferences
+		}
+		return nil, resolutionError
+	}
+
+	return parametersWithValues, nil
+}
+
+//
+// Types a value may have when it is referenced with one of these prefixes
+var expectedTypesByPrefix = map[string][]string{
+	ssmSecurePrefix:      {secureStringType},
+	ssmListPrefix:        {stringListType},
+	secretsManagerPrefix: {secretStringType, secretBinaryType},
+	vaultPrefix:          {vaultSecretType},
+}
+
+func validateParameterReferencePrefix(resolvedParametersMap *map[string]SsmParameterInfo) []ParameterTypeMismatch {
+	typeMismatches := []ParameterTypeMismatch{}
+
 	for key, value := range *resolvedParametersMap {
-		if strings.HasPrefix(key, ssmSecurePrefix) && value.Type != secureStringType {
-			return errors.New("for parameter reference {{" + key + "}} secure prefix " + ssmSecurePrefix + " is used for a non-secure type " + value.Type)
+		prefix := referencePrefix(key)
+
+		if expectedTypes, found := expectedTypesByPrefix[prefix]; found && !containsString(expectedTypes, value.Type) {
+			typeMismatches = append(typeMismatches, ParameterTypeMismatch{Reference: key, Prefix: prefix, Type: value.Type})
 		}
 
-		if strings.HasPrefix(key, ssmNonSecurePrefix) && value.Type == secureStringType {
-			return errors.New("for parameter reference {{" + key + "}} non-secure prefix " + ssmNonSecurePrefix + " is used for a secure type " + value.Type)
+		if prefix == ssmNonSecurePrefix && value.Type == secureStringType {
+			typeMismatches = append(typeMismatches, ParameterTypeMismatch{Reference: key, Prefix: ssmNonSecurePrefix, Type: value.Type})
 		}
 	}
 
-	return nil
+	sort.Slice(typeMismatches, func(i, j int) bool { return typeMismatches[i].Reference < typeMismatches[j].Reference })
+	return typeMismatches
+}
+
+func containsString(slice []string, value string) bool {
+	for _, element := range slice {
+		if element == value {
+			return true
+		}
+	}
+	return false
 }
 
 func dedupSlice(slice []string) []string {
@@ -163,27 +304,3 @@ func dedupSlice(slice []string) []string {
 
 	return keys
 }
-
-func parseParametersFromTextIntoDedupedSlice(text string, ignoreSecureParameters bool) ([]string, error) {
-
-	matchedPhrases := parameterPlaceholder.FindAllStringSubmatch(text, -1)
-
-	parameterNamesDeduped := make(map[string]bool)
-	for i := 0; i < len(matchedPhrases); i++ {
-		parameterNamesDeduped[matchedPhrases[i][1]] = true
-	}
-
-	if !ignoreSecureParameters {
-		matchedSecurePhrases := secureParameterPlaceholder.FindAllStringSubmatch(text, -1)
-		for i := 0; i < len(matchedSecurePhrases); i++ {
-			parameterNamesDeduped[matchedSecurePhrases[i][1]] = true
-		}
-	}
-
-	result := []string{}
-	for key := range parameterNamesDeduped {
-		result = append(result, key)
-	}
-
-	return result, nil
-}
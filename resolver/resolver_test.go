@@ -1,6 +1,8 @@
 package resolver
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"sort"
 	"testing"
@@ -62,6 +64,25 @@ func TestExtractParametersFromTextWrongPrefix(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestExtractParametersFromTextReportsAllErrors(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:param1":        {Name: "param1", Type: secureStringType, Value: "value_param1"},
+		"ssm-secure:param2": {Name: "param2", Type: stringType, Value: "value_param2"},
+		"ssm:param3":        {Name: "param3", Type: stringType, Value: "value_param3"},
+	})
+
+	text := "{{ssm:param1}} {{ssm-secure:param2}} {{ssm:param3}} {{ssm:missing1}} {{ssm-secure:missing2}}"
+	_, err := ExtractParametersFromText(&serviceObject, text, ResolveOptions{})
+
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, []string{"ssm-secure:missing2", "ssm:missing1"}, resolutionError.MissingReferences)
+	assert.Equal(t, []ParameterTypeMismatch{
+		{Reference: "ssm-secure:param2", Prefix: ssmSecurePrefix, Type: stringType},
+		{Reference: "ssm:param1", Prefix: ssmNonSecurePrefix, Type: secureStringType},
+	}, resolutionError.TypeMismatches)
+}
+
 func TestResolveParameterReferenceList(t *testing.T) {
 	expectedResult := map[string]SsmParameterInfo{
 		"ssm:param1":             {Name: "param1", Type: stringType, Value: "value_param1"},
@@ -114,11 +135,20 @@ func TestResolveParameterReferenceListIgnoreSecureParams(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(resolvedParameters, expectedResult))
 }
 
+// Deduplicated references of the placeholders in text, validated like the resolve functions do
+func scanReferences(text string, options ResolveOptions) ([]string, error) {
+	references, _, err := newPlaceholderScanner(ssmReferencePrefixes, options).scan(text)
+	if err != nil {
+		return nil, err
+	}
+	return references, validateParameterReferences(references, ssmReferencePrefixes)
+}
+
 func TestParseParametersFromTextIntoDedupedSliceSecureNotAllowed(t *testing.T) {
 	text := "Some text {{ ssm:/a/b/c/param1}}, some more text {{ssm-secure:param2}}, {{ ssm-secure:/a/b/c/param1  }}."
 	expectedList := []string{"ssm:/a/b/c/param1"}
 
-	list, err := parseParametersFromTextIntoDedupedSlice(text, true)
+	list, err := scanReferences(text, ResolveOptions{IgnoreSecureParameters: true})
 
 	assert.Nil(t, err)
 	assert.NotNil(t, list)
@@ -132,7 +162,7 @@ func TestParseParametersFromTextIntoDedupedSliceSecureAllowed(t *testing.T) {
 	text := "Some text {{ ssm:/a/b/c/param1}}, some more text {{ssm-secure:param2}}, {{ ssm-secure:/a/b/c/param1  }}."
 	expectedList := []string{"ssm:/a/b/c/param1", "ssm-secure:param2", "ssm-secure:/a/b/c/param1"}
 
-	list, err := parseParametersFromTextIntoDedupedSlice(text, false)
+	list, err := scanReferences(text, ResolveOptions{})
 
 	assert.Nil(t, err)
 	assert.NotNil(t, list)
@@ -142,6 +172,29 @@ func TestParseParametersFromTextIntoDedupedSliceSecureAllowed(t *testing.T) {
 	assert.True(t, reflect.DeepEqual(list, expectedList))
 }
 
+func TestParseParametersFromTextIntoDedupedSliceWithSelectors(t *testing.T) {
+	text := "{{ssm:/a/b/c:3}} {{ ssm-secure:param2:prod }} {{ssm:/a/b/c}} {{ssm:/a/b/c:3}}"
+	expectedList := []string{"ssm:/a/b/c", "ssm:/a/b/c:3", "ssm-secure:param2:prod"}
+
+	list, err := scanReferences(text, ResolveOptions{})
+
+	assert.Nil(t, err)
+	sort.Strings(expectedList)
+	sort.Strings(list)
+	assert.Equal(t, expectedList, list)
+}
+
+func TestResolveParametersInTextWithDotsInNames(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/db.host": {Name: "/app/db.host", Type: stringType, Value: "db.example.com"},
+	})
+
+	output, err := ResolveParametersInText(&serviceObject, "host={{ ssm:/app/db.host }}", ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "host=db.example.com", output)
+}
+
 func TestResolveParametersInText(t *testing.T) {
 	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
 		"ssm:/a/b/c/param1": {Name: "/a/b/c/param1", Type: stringType, Value: "value_/a/b/c/param1"},
@@ -177,3 +230,91 @@ func TestResolveParametersInTextIgnoreSecureParams(t *testing.T) {
 	assert.NotNil(t, output)
 	assert.True(t, expectedOutput == output)
 }
+
+func TestResolveParametersInTextWithProviderFunc(t *testing.T) {
+	provider := ParameterProviderFunc(func(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+		result := map[string]SsmParameterInfo{}
+		for _, ref := range parameterReferences {
+			name := extractParameterNameFromReference(ref)
+			result[ref] = SsmParameterInfo{Name: name, Type: stringType, Value: "func_" + name}
+		}
+		return result, nil
+	})
+
+	output, err := ResolveParametersInText(provider, "value: {{ssm:param1}}", ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value: func_param1", output)
+}
+
+func TestResolveParametersInTextWithContextCancelled(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:param1": {Name: "param1", Type: stringType, Value: "value_param1"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ResolveParametersInTextWithContext(ctx, &serviceObject, "{{ssm:param1}}", ResolveOptions{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestResolveParametersInTextWithDefaults(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/flags/beta": {Name: "/flags/beta", Type: stringType, Value: "on"},
+	})
+
+	text := `beta={{ssm:/flags/beta || "off"}} gamma={{ ssm:/flags/gamma || "off" }} ` +
+		`delta={{?ssm:/flags/delta}} epsilon={{ ? ssm:/flags/epsilon || "a \"quoted\" || value" }}`
+	output, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, `beta=on gamma=off delta= epsilon=a "quoted" || value`, output)
+}
+
+func TestExtractParametersFromTextLeavesOutMissingOptionalReferences(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{})
+
+	resolvedParameters, err := ExtractParametersFromText(&serviceObject, `{{ssm:/flags/gamma || "off"}}`, ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(resolvedParameters))
+
+	// a reference is only optional when every placeholder using it has a default
+	_, err = ExtractParametersFromText(&serviceObject, `{{ssm:/flags/gamma || "off"}} {{ssm:/flags/gamma}}`, ResolveOptions{})
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, []string{"ssm:/flags/gamma"}, resolutionError.MissingReferences)
+}
+
+func TestSecureReferencesRequireOptInForDefaults(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{})
+
+	for _, text := range []string{`{{ssm-secure:/db/password || "changeme"}}`, `{{?ssm-secure:/db/password}}`} {
+		_, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{})
+		var invalidReferenceError *InvalidParameterReferenceError
+		assert.True(t, errors.As(err, &invalidReferenceError), text)
+	}
+
+	output, err := ResolveParametersInText(&serviceObject, `{{ssm-secure:/db/password || "changeme"}}`, ResolveOptions{AllowSecureDefaults: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "changeme", output)
+}
+
+func TestMalformedDefaultValues(t *testing.T) {
+	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{})
+
+	for _, text := range []string{`{{ssm:/a || off}}`, `{{ssm:/a || "off}}`, `{{ssm:/a || }}`} {
+		_, err := ResolveParametersInText(&serviceObject, text, ResolveOptions{})
+		var invalidReferenceError *InvalidParameterReferenceError
+		assert.True(t, errors.As(err, &invalidReferenceError), text)
+	}
+
+	// braces in a quoted default don't end the placeholder
+	output, err := ResolveParametersInText(&serviceObject, `a={{ssm:/a || "{}"}} b={{ ssm:/b || "}}{{" }}`, ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "a={} b=}}{{", output)
+
+	_, err = ResolveParametersInText(&serviceObject, `{{ssm:/a || "{}" x}}`, ResolveOptions{})
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+}
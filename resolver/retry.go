@@ -0,0 +1,103 @@
+package resolver
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+//
+// RetryPolicy controls how Service retries SSM calls failed with throttling or transient errors.
+// The delay before retry n is BaseDelay * 2^(n-1), capped at MaxDelay when it is set, of which the Jitter
+// fraction (clamped to 0..1) is randomized. A zero RetryPolicy disables retries.
+type RetryPolicy struct {
+	// Total number of attempts including the first one
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      float64
+}
+
+//
+// Retry policy used by NewService
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+	Jitter:      0.5,
+}
+
+//
+// Calls operation until it succeeds, fails with a permanent error or the attempts are exhausted.
+// It never sleeps past the deadline of ctx: when the next delay would not fit, the last error is returned.
+func (p RetryPolicy) do(ctx context.Context, operation func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || attempt >= p.MaxAttempts || !isRetryableError(err) {
+			return err
+		}
+
+		delay := p.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay <= math.MaxInt64/2; i++ {
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	jitter := math.Min(math.Max(p.Jitter, 0), 1)
+	if jitter > 0 {
+		randomized := time.Duration(float64(delay) * jitter * rand.Float64())
+		delay = delay - time.Duration(float64(delay)*jitter) + randomized
+	}
+	return delay
+}
+
+//
+// Throttling, server side failures and network errors are retried. Everything else - access errors,
+// validation errors, cancelled requests - is permanent.
+func isRetryableError(err error) bool {
+	if isContextError(err) {
+		return false
+	}
+
+	awsError, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+
+	if request.IsErrorThrottle(awsError) || awsError.Code() == ssm.ErrCodeInternalServerError {
+		return true
+	}
+
+	if requestFailure, ok := awsError.(awserr.RequestFailure); ok && requestFailure.StatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+
+	return request.IsErrorRetryable(awsError)
+}
@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+//
+// Fake SSM client that fails the first failures calls with err
+type failingSsmClientMock struct {
+	ssmClientMock
+	err      error
+	failures int
+	calls    int
+}
+
+func (m *failingSsmClientMock) GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error) {
+	m.calls++
+	if m.calls <= m.failures {
+		return nil, m.err
+	}
+	return m.ssmClientMock.GetParametersWithContext(ctx, input, opts...)
+}
+
+func newFailingSsmClientMock(err error, failures int) *failingSsmClientMock {
+	return &failingSsmClientMock{
+		ssmClientMock: ssmClientMock{parameters: map[string]*ssm.Parameter{
+			"param": {Name: aws.String("param"), Type: aws.String(stringType), Value: aws.String("value")},
+		}},
+		err:      err,
+		failures: failures,
+	}
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond, Jitter: 0.5}
+
+func TestServiceRetriesThrottling(t *testing.T) {
+	client := newFailingSsmClientMock(awserr.New("ThrottlingException", "Rate exceeded", nil), 3)
+	service := &Service{SSMClient: client, RetryPolicy: testRetryPolicy}
+
+	result, err := service.GetParameters(context.Background(), []string{"ssm:param"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value", result["ssm:param"].Value)
+	assert.Equal(t, 4, client.calls)
+}
+
+func TestServiceGivesUpAfterMaxAttempts(t *testing.T) {
+	throttlingError := awserr.New("ThrottlingException", "Rate exceeded", nil)
+	client := newFailingSsmClientMock(throttlingError, 10)
+	service := &Service{SSMClient: client, RetryPolicy: testRetryPolicy}
+
+	_, err := service.GetParameters(context.Background(), []string{"ssm:param"})
+
+	assert.Equal(t, throttlingError, err)
+	assert.Equal(t, testRetryPolicy.MaxAttempts, client.calls)
+}
+
+func TestServiceDoesNotRetryPermanentErrors(t *testing.T) {
+	client := newFailingSsmClientMock(awserr.New("AccessDeniedException", "not authorized", nil), 1)
+	service := &Service{SSMClient: client, RetryPolicy: testRetryPolicy}
+
+	_, err := service.GetParameters(context.Background(), []string{"ssm:param"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, client.calls)
+
+	client = newFailingSsmClientMock(nil, 0)
+	service = &Service{SSMClient: client, RetryPolicy: testRetryPolicy}
+
+	_, err = service.GetParameters(context.Background(), []string{"ssm:missing"})
+
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestServiceRetryRespectsDeadline(t *testing.T) {
+	client := newFailingSsmClientMock(awserr.New("ThrottlingException", "Rate exceeded", nil), 10)
+	service := &Service{SSMClient: client, RetryPolicy: RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour, MaxDelay: time.Hour}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := service.GetParameters(ctx, []string{"ssm:param"})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, client.calls)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 400*time.Millisecond, policy.delay(3))
+	assert.Equal(t, time.Second, policy.delay(10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.delay(2)
+		assert.True(t, delay >= 100*time.Millisecond && delay <= 200*time.Millisecond)
+	}
+
+	// without MaxDelay the delay keeps doubling
+	uncapped := RetryPolicy{BaseDelay: 100 * time.Millisecond}
+	assert.Equal(t, 200*time.Millisecond, uncapped.delay(2))
+	assert.Equal(t, 1600*time.Millisecond, uncapped.delay(5))
+	assert.True(t, uncapped.delay(100) > 0)
+
+	// Jitter is clamped to 0..1, so the delay never goes negative
+	policy.Jitter = 2
+	for i := 0; i < 100; i++ {
+		delay := policy.delay(2)
+		assert.True(t, delay >= 0 && delay <= 200*time.Millisecond)
+	}
+	policy.Jitter = -1
+	assert.Equal(t, 200*time.Millisecond, policy.delay(2))
+}
+
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, isRetryableError(awserr.New("ThrottlingException", "", nil)))
+	assert.True(t, isRetryableError(awserr.New(ssm.ErrCodeInternalServerError, "", nil)))
+	assert.True(t, isRetryableError(awserr.NewRequestFailure(awserr.New("ServiceUnavailable", "", nil), 503, "id")))
+	assert.False(t, isRetryableError(awserr.New(ssm.ErrCodeInvalidKeyId, "", nil)))
+	assert.False(t, isRetryableError(awserr.New(request.CanceledErrorCode, "", context.Canceled)))
+	assert.False(t, isRetryableError(context.DeadlineExceeded))
+	assert.False(t, isRetryableError(errors.New("unknown")))
+}
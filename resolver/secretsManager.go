@@ -0,0 +1,230 @@
+package resolver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+//
+// Number of colon separated fields in a secret ARN (arn:partition:secretsmanager:region:account:secret:name)
+const secretArnFields = 7
+
+var secretNameCharacters = regexp.MustCompile(`^[a-zA-Z0-9/_+=.@\-]{1,512}$`)
+var secretArn = regexp.MustCompile(`^arn:aws[a-z-]*:secretsmanager:[a-z0-9-]+:\d{12}:secret:[a-zA-Z0-9/_+=.@\-]+$`)
+
+//
+// SecretsManagerProvider resolves references to AWS Secrets Manager secrets:
+//
+//	{{secretsmanager:secret-id}}                           - the whole secret value
+//	{{secretsmanager:secret-id:json-key}}                  - one top level key of a JSON secret
+//	{{secretsmanager:secret-id:json-key:version-stage}}    - same for a version stage, e.g. AWSPREVIOUS
+//	{{secretsmanager:secret-id::version-stage}}            - the whole value of a version stage
+//	{{secretsmanager:secret-id:json-key::version-id}}      - a specific version
+//
+// The secret id is a secret name or a full secret ARN. Secret values are secure, they are skipped
+// when ResolveOptions.IgnoreSecureParameters is set.
+type SecretsManagerProvider struct {
+	SecretsManagerClient secretsmanageriface.SecretsManagerAPI
+	RetryPolicy          RetryPolicy
+}
+
+var _ IPrefixedParameterProvider = (*SecretsManagerProvider)(nil)
+
+//
+// Creates a SecretsManagerProvider configured by the same options as NewServiceWithOptions
+func NewSecretsManagerProviderWithOptions(options ...ServiceOption) (*SecretsManagerProvider, error) {
+	config := newServiceConfig(options)
+
+	currentSession, clientConfig, err := newAwsSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretsManagerProvider{
+		SecretsManagerClient: secretsmanager.New(currentSession, clientConfig),
+		RetryPolicy:          config.retryPolicy,
+	}, nil
+}
+
+func (p *SecretsManagerProvider) ReferencePrefixes() []ReferencePrefix {
+	return []ReferencePrefix{{Prefix: secretsManagerPrefix, Secure: true}}
+}
+
+//
+// Parsed form of a secret reference like secretsmanager:name:json-key:version-stage:version-id
+type secretReference struct {
+	secretID     string
+	jsonKey      string
+	versionStage string
+	versionID    string
+}
+
+func parseSecretReference(reference string) (secretReference, string) {
+	body := strings.TrimPrefix(reference, secretsManagerPrefix)
+
+	// an ARN brings its own colons, the optional fields start after it
+	idFields := 1
+	if strings.HasPrefix(body, arnPrefix) {
+		idFields = secretArnFields
+	}
+	fields := strings.SplitN(body, ":", idFields+3)
+
+	result := secretReference{}
+	if len(fields) < idFields {
+		return result, "malformed secret ARN"
+	}
+	result.secretID = strings.Join(fields[:idFields], ":")
+	if len(fields) > idFields {
+		result.jsonKey = fields[idFields]
+	}
+	if len(fields) > idFields+1 {
+		result.versionStage = fields[idFields+1]
+	}
+	if len(fields) > idFields+2 {
+		result.versionID = fields[idFields+2]
+	}
+
+	if idFields == secretArnFields && !secretArn.MatchString(result.secretID) {
+		return result, "malformed secret ARN"
+	}
+	if idFields == 1 && !secretNameCharacters.MatchString(result.secretID) {
+		return result, "secret name must be 1 to 512 letters, numbers and the symbols /_+=.@-"
+	}
+	if strings.Contains(result.versionID, ":") {
+		return result, "too many fields, expected secret-id:json-key:version-stage:version-id"
+	}
+	if result.versionStage != "" && result.versionID != "" {
+		return result, "either version stage or version id can be selected, not both"
+	}
+
+	return result, ""
+}
+
+func (p *SecretsManagerProvider) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	type secretVersion struct {
+		secretID     string
+		versionStage string
+		versionID    string
+	}
+
+	versionOrder := []secretVersion{}
+	referencesByVersion := map[secretVersion][]string{}
+	parsedReferences := map[string]secretReference{}
+	invalidReferences := []InvalidParameterReference{}
+
+	for _, ref := range parameterReferences {
+		parsed, reason := parseSecretReference(ref)
+		if reason != "" {
+			invalidReferences = append(invalidReferences, InvalidParameterReference{Reference: ref, Reason: reason})
+			continue
+		}
+
+		parsedReferences[ref] = parsed
+		version := secretVersion{secretID: parsed.secretID, versionStage: parsed.versionStage, versionID: parsed.versionID}
+		if _, found := referencesByVersion[version]; !found {
+			versionOrder = append(versionOrder, version)
+		}
+		referencesByVersion[version] = append(referencesByVersion[version], ref)
+	}
+
+	if len(invalidReferences) > 0 {
+		sort.Slice(invalidReferences, func(i, j int) bool { return invalidReferences[i].Reference < invalidReferences[j].Reference })
+		return nil, &InvalidParameterReferenceError{References: invalidReferences}
+	}
+
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+
+	// different JSON keys of one secret version are served by a single request
+	for _, version := range versionOrder {
+		input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(version.secretID)}
+		if version.versionStage != "" {
+			input.VersionStage = aws.String(version.versionStage)
+		}
+		if version.versionID != "" {
+			input.VersionId = aws.String(version.versionID)
+		}
+
+		var output *secretsmanager.GetSecretValueOutput
+		err := p.RetryPolicy.do(ctx, func() (err error) {
+			output, err = p.SecretsManagerClient.GetSecretValueWithContext(ctx, input)
+			return err
+		})
+		if awsError, ok := err.(awserr.Error); ok && awsError.Code() == secretsmanager.ErrCodeResourceNotFoundException {
+			missingReferences = append(missingReferences, referencesByVersion[version]...)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range referencesByVersion[version] {
+			value, found := secretValue(output, parsedReferences[ref].jsonKey)
+			if !found {
+				missingReferences = append(missingReferences, ref)
+				continue
+			}
+
+			secretType := secretStringType
+			if output.SecretString == nil {
+				secretType = secretBinaryType
+			}
+			result[ref] = SsmParameterInfo{
+				Name:  aws.StringValue(output.Name),
+				Type:  secretType,
+				Value: value,
+				ARN:   aws.StringValue(output.ARN),
+			}
+		}
+	}
+
+	if len(missingReferences) > 0 {
+		return result, newMissingParametersError(missingReferences)
+	}
+	return result, nil
+}
+
+//
+// Returns the secret value, or the value under jsonKey if it is set. Binary secrets are base64 encoded,
+// JSON values other than strings are returned as JSON text.
+func secretValue(output *secretsmanager.GetSecretValueOutput, jsonKey string) (string, bool) {
+	if output.SecretString == nil {
+		if jsonKey != "" {
+			return "", false
+		}
+		return base64.StdEncoding.EncodeToString(output.SecretBinary), true
+	}
+
+	if jsonKey == "" {
+		return *output.SecretString, true
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(*output.SecretString), &fields); err != nil {
+		return "", false
+	}
+
+	field, found := fields[jsonKey]
+	if !found {
+		return "", false
+	}
+	return jsonValueAsText(field), true
+}
+
+//
+// Returns JSON strings unquoted and any other JSON value as JSON text
+func jsonValueAsText(value json.RawMessage) string {
+	var stringValue string
+	if err := json.Unmarshal(value, &stringValue); err == nil {
+		return stringValue
+	}
+	return string(value)
+}
@@ -0,0 +1,164 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/stretchr/testify/assert"
+)
+
+// Fake Secrets Manager client that serves secrets keyed by secret-id, secret-id:version-stage or secret-id::version-id
+type secretsManagerClientMock struct {
+	secretsmanageriface.SecretsManagerAPI
+	secrets  map[string]*secretsmanager.GetSecretValueOutput
+	requests []string
+}
+
+func (m *secretsManagerClientMock) GetSecretValueWithContext(ctx aws.Context, input *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	key := *input.SecretId
+	if input.VersionStage != nil {
+		key += ":" + *input.VersionStage
+	}
+	if input.VersionId != nil {
+		key += "::" + *input.VersionId
+	}
+	m.requests = append(m.requests, key)
+
+	if secret, found := m.secrets[key]; found {
+		return secret, nil
+	}
+	return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secrets Manager can't find the specified secret.", nil)
+}
+
+const testSecretArn = "arn:aws:secretsmanager:us-east-1:123456789012:secret:db-AbCdEf"
+
+func newSecretsManagerClientMock() *secretsManagerClientMock {
+	return &secretsManagerClientMock{secrets: map[string]*secretsmanager.GetSecretValueOutput{
+		"db": {Name: aws.String("db"), ARN: aws.String(testSecretArn),
+			SecretString: aws.String(`{"username":"admin","password":"p@ss","port":5432}`)},
+		"db:AWSPREVIOUS": {Name: aws.String("db"), ARN: aws.String(testSecretArn),
+			SecretString: aws.String(`{"username":"admin","password":"old"}`)},
+		testSecretArn: {Name: aws.String("db"), ARN: aws.String(testSecretArn),
+			SecretString: aws.String(`{"username":"admin","password":"p@ss"}`)},
+		"token": {Name: aws.String("token"), SecretString: aws.String("plain-token")},
+		"cert":  {Name: aws.String("cert"), SecretBinary: []byte{0x01, 0x02}},
+	}}
+}
+
+func TestParseSecretReference(t *testing.T) {
+	testCases := map[string]secretReference{
+		"secretsmanager:db":                          {secretID: "db"},
+		"secretsmanager:db:password":                 {secretID: "db", jsonKey: "password"},
+		"secretsmanager:db:password:AWSPREVIOUS":     {secretID: "db", jsonKey: "password", versionStage: "AWSPREVIOUS"},
+		"secretsmanager:db::AWSPREVIOUS":             {secretID: "db", versionStage: "AWSPREVIOUS"},
+		"secretsmanager:" + testSecretArn:            {secretID: testSecretArn},
+		"secretsmanager:" + testSecretArn + ":user":  {secretID: testSecretArn, jsonKey: "user"},
+		"secretsmanager:app/prod/db+1=x.y@z-w:field": {secretID: "app/prod/db+1=x.y@z-w", jsonKey: "field"},
+	}
+	for reference, expected := range testCases {
+		parsed, reason := parseSecretReference(reference)
+		assert.Equal(t, "", reason, reference)
+		assert.Equal(t, expected, parsed, reference)
+	}
+
+	for _, reference := range []string{
+		"secretsmanager:",
+		"secretsmanager:db name",
+		"secretsmanager:db:key:stage:extra",
+		"secretsmanager:arn:aws:ssm:us-east-1:123456789012:parameter/x",
+	} {
+		_, reason := parseSecretReference(reference)
+		assert.NotEqual(t, "", reason, reference)
+	}
+}
+
+func TestSecretsManagerProviderGetParameters(t *testing.T) {
+	client := newSecretsManagerClientMock()
+	provider := &SecretsManagerProvider{SecretsManagerClient: client}
+
+	result, err := provider.GetParameters(context.Background(), []string{
+		"secretsmanager:db:username",
+		"secretsmanager:db:password",
+		"secretsmanager:db:port",
+		"secretsmanager:db:password:AWSPREVIOUS",
+		"secretsmanager:" + testSecretArn + ":password",
+		"secretsmanager:token",
+		"secretsmanager:cert",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, SsmParameterInfo{Name: "db", Type: secretStringType, Value: "admin", ARN: testSecretArn}, result["secretsmanager:db:username"])
+	assert.Equal(t, "p@ss", result["secretsmanager:db:password"].Value)
+	assert.Equal(t, "5432", result["secretsmanager:db:port"].Value)
+	assert.Equal(t, "old", result["secretsmanager:db:password:AWSPREVIOUS"].Value)
+	assert.Equal(t, "p@ss", result["secretsmanager:"+testSecretArn+":password"].Value)
+	assert.Equal(t, "plain-token", result["secretsmanager:token"].Value)
+	assert.Equal(t, SsmParameterInfo{Name: "cert", Type: secretBinaryType, Value: "AQI="}, result["secretsmanager:cert"])
+	assert.Equal(t, []string{"db", "db:AWSPREVIOUS", testSecretArn, "token", "cert"}, client.requests)
+}
+
+func TestSecretsManagerProviderReportsMissingSecretsAndKeys(t *testing.T) {
+	provider := &SecretsManagerProvider{SecretsManagerClient: newSecretsManagerClientMock()}
+
+	result, err := provider.GetParameters(context.Background(), []string{
+		"secretsmanager:db:password",
+		"secretsmanager:db:missing-key",
+		"secretsmanager:missing-secret",
+		"secretsmanager:token:key",
+	})
+
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, []string{
+		"secretsmanager:db:missing-key",
+		"secretsmanager:missing-secret",
+		"secretsmanager:token:key",
+	}, missingParametersError.References)
+	assert.Equal(t, "p@ss", result["secretsmanager:db:password"].Value)
+}
+
+func TestResolveParametersInTextWithSsmAndSecretsManager(t *testing.T) {
+	ssmService := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/db/host": {Name: "/app/db/host", Type: stringType, Value: "db.example.com"},
+	})
+	router := NewProviderRouter().
+		Register(&ssmService).
+		Register(&SecretsManagerProvider{SecretsManagerClient: newSecretsManagerClientMock()})
+
+	text := "postgres://{{secretsmanager:db:username}}:{{ secretsmanager:db:password }}@{{ssm:/app/db/host}}/app"
+
+	output, err := ResolveParametersInText(router, text, ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://admin:p@ss@db.example.com/app", output)
+
+	output, err = ResolveParametersInText(router, text, ResolveOptions{IgnoreSecureParameters: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "postgres://{{secretsmanager:db:username}}:{{ secretsmanager:db:password }}@db.example.com/app", output)
+}
+
+func TestResolveParametersInTextIgnoresUnregisteredPrefixes(t *testing.T) {
+	ssmService := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:param": {Name: "param", Type: stringType, Value: "value"},
+	})
+
+	output, err := ResolveParametersInText(&ssmService, "{{ssm:param}} {{secretsmanager:db}}", ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value {{secretsmanager:db}}", output)
+}
+
+func TestResolveParametersInTextWithSecretNameSymbols(t *testing.T) {
+	client := newSecretsManagerClientMock()
+	client.secrets["app/db+1"] = &secretsmanager.GetSecretValueOutput{Name: aws.String("app/db+1"), SecretString: aws.String("value")}
+
+	output, err := ResolveParametersInText(&SecretsManagerProvider{SecretsManagerClient: client}, "{{secretsmanager:app/db+1}}", ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value", output)
+}
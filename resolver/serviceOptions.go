@@ -0,0 +1,210 @@
+package resolver
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+//
+// Environment variable read by WithAssumeRoleArnFromEnv
+const AssumeRoleArnEnvVariable = "SSM2ENV_ASSUME_ROLE_ARN"
+
+//
+// ServiceOption configures the Service created by NewServiceWithOptions
+// and the SecretsManagerProvider created by NewSecretsManagerProviderWithOptions
+type ServiceOption func(config *serviceConfig)
+
+type serviceConfig struct {
+	region          string
+	profile         string
+	assumeRoleArn   string
+	externalID      string
+	roleSessionName string
+	roleDuration    time.Duration
+	endpoint        string
+	httpClient      *http.Client
+	maxRetries      *int
+	retryPolicy     RetryPolicy
+}
+
+//
+// AWS region of the SSM endpoint. Without it the region comes from the shared config or,
+// failing that, from EC2 instance metadata.
+func WithRegion(region string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.region = region
+	}
+}
+
+//
+// Named profile from the shared AWS config and credentials files
+func WithProfile(profile string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.profile = profile
+	}
+}
+
+//
+// Role assumed through STS for all SSM calls
+func WithAssumeRoleArn(roleArn string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.assumeRoleArn = roleArn
+	}
+}
+
+//
+// Role from the SSM2ENV_ASSUME_ROLE_ARN environment variable, if it is set. This is what NewService does.
+func WithAssumeRoleArnFromEnv() ServiceOption {
+	return func(config *serviceConfig) {
+		if roleArn := os.Getenv(AssumeRoleArnEnvVariable); roleArn != "" {
+			config.assumeRoleArn = roleArn
+		}
+	}
+}
+
+//
+// External ID passed when assuming the role
+func WithExternalID(externalID string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.externalID = externalID
+	}
+}
+
+//
+// Session name used when assuming the role
+func WithRoleSessionName(sessionName string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.roleSessionName = sessionName
+	}
+}
+
+//
+// Lifetime of the assumed role credentials
+func WithRoleDuration(duration time.Duration) ServiceOption {
+	return func(config *serviceConfig) {
+		config.roleDuration = duration
+	}
+}
+
+//
+// Custom endpoint URL of the store, e.g. a LocalStack instance
+func WithEndpoint(endpoint string) ServiceOption {
+	return func(config *serviceConfig) {
+		config.endpoint = endpoint
+	}
+}
+
+//
+// HTTP client used for all AWS calls
+func WithHTTPClient(client *http.Client) ServiceOption {
+	return func(config *serviceConfig) {
+		config.httpClient = client
+	}
+}
+
+//
+// Number of retries made by the AWS SDK itself for each request. Each attempt of the RetryPolicy is a request,
+// so while a RetryPolicy is active the SDK doesn't retry unless this option is given.
+func WithMaxRetries(maxRetries int) ServiceOption {
+	return func(config *serviceConfig) {
+		config.maxRetries = &maxRetries
+	}
+}
+
+//
+// Retry policy of the created provider, DefaultRetryPolicy if not set. It replaces the retries of the AWS SDK, see WithMaxRetries.
+func WithRetryPolicy(policy RetryPolicy) ServiceOption {
+	return func(config *serviceConfig) {
+		config.retryPolicy = policy
+	}
+}
+
+//
+// Creates a Service configured by the given options
+func NewServiceWithOptions(options ...ServiceOption) (*Service, error) {
+	config := newServiceConfig(options)
+
+	currentSession, clientConfig, err := newAwsSession(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		SSMClient:   ssm.New(currentSession, clientConfig),
+		RetryPolicy: config.retryPolicy,
+	}, nil
+}
+
+func newServiceConfig(options []ServiceOption) serviceConfig {
+	config := serviceConfig{retryPolicy: DefaultRetryPolicy}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+//
+// Creates the AWS session and the configuration for the service client (endpoint and credentials)
+func newAwsSession(config serviceConfig) (*session.Session, *aws.Config, error) {
+	awsConfig := aws.Config{}
+	if config.region != "" {
+		awsConfig.Region = aws.String(config.region)
+	}
+	if config.httpClient != nil {
+		awsConfig.HTTPClient = config.httpClient
+	}
+	if config.maxRetries != nil {
+		awsConfig.MaxRetries = config.maxRetries
+	}
+
+	currentSession, err := session.NewSessionWithOptions(session.Options{
+		Config:            awsConfig,
+		Profile:           config.profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if aws.StringValue(currentSession.Config.Region) == "" {
+		log.Println("There is no explict region configuration, retriving ec2metadata...")
+		region, err := ec2metadata.New(currentSession).Region()
+		if err != nil {
+			return nil, nil, err
+		}
+		currentSession.Config.Region = aws.String(region)
+	}
+
+	// the custom endpoint is for the service client only, STS calls still go to AWS
+	clientConfig := &aws.Config{}
+	if config.endpoint != "" {
+		clientConfig.Endpoint = aws.String(config.endpoint)
+	}
+	// the retry policy retries throttled calls, SDK retries on top of it would multiply the requests
+	if config.maxRetries == nil && config.retryPolicy.MaxAttempts > 1 {
+		clientConfig.MaxRetries = aws.Int(0)
+	}
+	if config.assumeRoleArn != "" {
+		clientConfig.Credentials = stscreds.NewCredentials(currentSession, config.assumeRoleArn, func(provider *stscreds.AssumeRoleProvider) {
+			if config.externalID != "" {
+				provider.ExternalID = aws.String(config.externalID)
+			}
+			if config.roleSessionName != "" {
+				provider.RoleSessionName = config.roleSessionName
+			}
+			if config.roleDuration != 0 {
+				provider.Duration = config.roleDuration
+			}
+		})
+	}
+
+	return currentSession, clientConfig, nil
+}
@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func setTestAwsEnvironment(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret")
+	t.Setenv("AWS_CONFIG_FILE", t.TempDir()+"/config")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", t.TempDir()+"/credentials")
+}
+
+func TestNewServiceWithOptionsCustomEndpoint(t *testing.T) {
+	setTestAwsEnvironment(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "AmazonSSM.GetParameters", r.Header.Get("X-Amz-Target"))
+
+		var input struct{ Names []string }
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&input))
+		assert.Equal(t, []string{"/a/b"}, input.Names)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Parameters":[{"Name":"/a/b","Type":"String","Value":"local","Version":2}],"InvalidParameters":[]}`))
+	}))
+	defer server.Close()
+
+	service, err := NewServiceWithOptions(
+		WithRegion("us-west-2"),
+		WithEndpoint(server.URL),
+		WithHTTPClient(server.Client()),
+		WithMaxRetries(0))
+	assert.Nil(t, err)
+
+	result, err := service.GetParameters(context.Background(), []string{"ssm:/a/b"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, SsmParameterInfo{Name: "/a/b", Type: stringType, Value: "local", Version: 2}, result["ssm:/a/b"])
+	assert.Equal(t, 1, requests)
+}
+
+func TestServiceOptions(t *testing.T) {
+	t.Setenv(AssumeRoleArnEnvVariable, "arn:aws:iam::123456789012:role/from-env")
+	httpClient := &http.Client{}
+
+	config := serviceConfig{}
+	for _, option := range []ServiceOption{
+		WithRegion("us-west-2"),
+		WithProfile("dev"),
+		WithAssumeRoleArnFromEnv(),
+		WithExternalID("external-id"),
+		WithRoleSessionName("resolver"),
+		WithRoleDuration(time.Hour),
+		WithEndpoint("http://localhost:4566"),
+		WithHTTPClient(httpClient),
+		WithMaxRetries(1),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2}),
+	} {
+		option(&config)
+	}
+
+	maxRetries := 1
+	assert.Equal(t, serviceConfig{
+		region:          "us-west-2",
+		profile:         "dev",
+		assumeRoleArn:   "arn:aws:iam::123456789012:role/from-env",
+		externalID:      "external-id",
+		roleSessionName: "resolver",
+		roleDuration:    time.Hour,
+		endpoint:        "http://localhost:4566",
+		httpClient:      httpClient,
+		maxRetries:      &maxRetries,
+		retryPolicy:     RetryPolicy{MaxAttempts: 2},
+	}, config)
+
+	t.Setenv(AssumeRoleArnEnvVariable, "")
+	WithAssumeRoleArnFromEnv()(&config)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/from-env", config.assumeRoleArn)
+}
+
+func TestNewServiceWithOptionsUsesProfileRegion(t *testing.T) {
+	setTestAwsEnvironment(t)
+	configFile := t.TempDir() + "/config"
+	assert.Nil(t, os.WriteFile(configFile, []byte("[profile dev]\nregion = eu-central-1\n"), 0600))
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+
+	service, err := NewServiceWithOptions(WithProfile("dev"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "eu-central-1", *service.SSMClient.(*ssm.SSM).Config.Region)
+	assert.Equal(t, DefaultRetryPolicy, service.RetryPolicy)
+}
+
+func TestNewServiceWithOptionsDisablesSdkRetriesUnderRetryPolicy(t *testing.T) {
+	setTestAwsEnvironment(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"__type":"ThrottlingException","message":"Rate exceeded"}`))
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	service, err := NewServiceWithOptions(WithRegion("us-west-2"), WithEndpoint(server.URL), WithHTTPClient(server.Client()), WithRetryPolicy(policy))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, *service.SSMClient.(*ssm.SSM).Config.MaxRetries)
+
+	_, err = service.GetParameters(context.Background(), []string{"ssm:/a/b"})
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, requests)
+
+	// an explicit WithMaxRetries wins, without a retry policy the SDK keeps its own retries
+	service, err = NewServiceWithOptions(WithRegion("us-west-2"), WithRetryPolicy(policy), WithMaxRetries(2))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, *service.SSMClient.(*ssm.SSM).Config.MaxRetries)
+
+	provider, err := NewSecretsManagerProviderWithOptions(WithRegion("us-west-2"), WithRetryPolicy(RetryPolicy{}))
+	assert.Nil(t, err)
+	assert.Equal(t, -1, *provider.SecretsManagerClient.(*secretsmanager.SecretsManager).Config.MaxRetries)
+
+	provider, err = NewSecretsManagerProviderWithOptions(WithRegion("us-west-2"))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, *provider.SecretsManagerClient.(*secretsmanager.SecretsManager).Config.MaxRetries)
+}
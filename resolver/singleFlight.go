@@ -0,0 +1,156 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+//
+// SingleFlightProvider is an IParameterProvider that coalesces concurrent lookups of the same reference:
+// while a reference is being fetched, other goroutines asking for it wait for that request instead of
+// sending their own. References of one batch that are not in flight yet are still fetched in one request,
+// so it works with the batching of the resolve functions.
+// Put it under a ParameterCache to coalesce cache misses: NewParameterCache(NewSingleFlightProvider(service), ...).
+type SingleFlightProvider struct {
+	provider IParameterProvider
+
+	mutex sync.Mutex
+	calls map[string]*inFlightCall
+}
+
+var _ IPrefixedParameterProvider = (*SingleFlightProvider)(nil)
+
+type inFlightCall struct {
+	// closed when the result is available
+	done    chan struct{}
+	value   SsmParameterInfo
+	missing bool
+	err     error
+}
+
+func NewSingleFlightProvider(provider IParameterProvider) *SingleFlightProvider {
+	return &SingleFlightProvider{
+		provider: provider,
+		calls:    map[string]*inFlightCall{},
+	}
+}
+
+func (p *SingleFlightProvider) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	ownCalls := map[string]*inFlightCall{}
+	sharedCalls := map[string]*inFlightCall{}
+	referencesToFetch := []string{}
+
+	p.mutex.Lock()
+	for _, ref := range parameterReferences {
+		if _, found := ownCalls[ref]; found {
+			continue
+		}
+		if call, found := p.calls[ref]; found {
+			sharedCalls[ref] = call
+			continue
+		}
+
+		call := &inFlightCall{done: make(chan struct{})}
+		p.calls[ref] = call
+		ownCalls[ref] = call
+		referencesToFetch = append(referencesToFetch, ref)
+	}
+	p.mutex.Unlock()
+
+	if len(referencesToFetch) > 0 {
+		p.fetch(ctx, referencesToFetch, ownCalls)
+	}
+
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+	// references whose shared request was cancelled by the goroutine that made it
+	referencesToRetry := []string{}
+
+	collect := func(ref string, call *inFlightCall) error {
+		switch {
+		case call.err != nil:
+			return call.err
+		case call.missing:
+			missingReferences = append(missingReferences, ref)
+		default:
+			result[ref] = call.value
+		}
+		return nil
+	}
+
+	for ref, call := range ownCalls {
+		if err := collect(ref, call); err != nil {
+			return nil, err
+		}
+	}
+
+	for ref, call := range sharedCalls {
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if isContextError(call.err) && ctx.Err() == nil {
+			referencesToRetry = append(referencesToRetry, ref)
+			continue
+		}
+		if err := collect(ref, call); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(referencesToRetry) > 0 {
+		retried, err := p.GetParameters(ctx, referencesToRetry)
+		var missingParametersError *MissingParametersError
+		if errors.As(err, &missingParametersError) {
+			missingReferences = append(missingReferences, missingParametersError.References...)
+		} else if err != nil {
+			return nil, err
+		}
+		for ref, value := range retried {
+			result[ref] = value
+		}
+	}
+
+	if len(missingReferences) > 0 {
+		return result, newMissingParametersError(missingReferences)
+	}
+	return result, nil
+}
+
+func (p *SingleFlightProvider) ReferencePrefixes() []ReferencePrefix {
+	return referencePrefixesOf(p.provider)
+}
+
+//
+// Fetches the references owned by this goroutine and publishes the results to waiting goroutines
+func (p *SingleFlightProvider) fetch(ctx context.Context, parameterReferences []string, calls map[string]*inFlightCall) {
+	results, err := p.provider.GetParameters(ctx, parameterReferences)
+
+	// missing references are the ones absent from results
+	var missingParametersError *MissingParametersError
+	if errors.As(err, &missingParametersError) {
+		err = nil
+	}
+
+	p.mutex.Lock()
+	for _, ref := range parameterReferences {
+		call := calls[ref]
+		if value, found := results[ref]; found && err == nil {
+			call.value = value
+		} else if err != nil {
+			call.err = err
+		} else {
+			call.missing = true
+		}
+		delete(p.calls, ref)
+		close(call.done)
+	}
+	p.mutex.Unlock()
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
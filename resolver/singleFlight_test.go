@@ -0,0 +1,142 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//
+// Provider that blocks every request until release is closed
+type blockingProviderMock struct {
+	*countingProviderMock
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *blockingProviderMock) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	m.started <- struct{}{}
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return m.countingProviderMock.GetParameters(ctx, parameterReferences)
+}
+
+func newBlockingProviderMock(records map[string]SsmParameterInfo) *blockingProviderMock {
+	return &blockingProviderMock{
+		countingProviderMock: newCountingProviderMock(records),
+		started:              make(chan struct{}, 100),
+		release:              make(chan struct{}),
+	}
+}
+
+func TestSingleFlightProviderCoalescesConcurrentLookups(t *testing.T) {
+	const waiters = 5
+	records := map[string]SsmParameterInfo{}
+	for ref, value := range cacheTestRecords {
+		records[ref] = value
+	}
+	for i := 0; i < waiters; i++ {
+		name := "own" + strconv.Itoa(i)
+		records["ssm:"+name] = SsmParameterInfo{Name: name, Type: stringType, Value: "value_" + name}
+	}
+
+	provider := newBlockingProviderMock(records)
+	singleFlight := NewSingleFlightProvider(provider)
+
+	var wg sync.WaitGroup
+	resolve := func(references []string) {
+		defer wg.Done()
+		result, err := singleFlight.GetParameters(context.Background(), references)
+		assert.Nil(t, err)
+		assert.Equal(t, len(references), len(result))
+		for _, ref := range references {
+			assert.Equal(t, records[ref], result[ref])
+		}
+	}
+
+	wg.Add(1)
+	go resolve([]string{"ssm:param1", "ssm-secure:param3"})
+	<-provider.started
+
+	// every waiter fetches a reference of its own, so it has joined the call for ssm:param1 once its request started
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go resolve([]string{"ssm:param1", "ssm:own" + strconv.Itoa(i)})
+	}
+	for i := 0; i < waiters; i++ {
+		<-provider.started
+	}
+
+	close(provider.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, provider.requests["ssm:param1"])
+	assert.Equal(t, 1, provider.requests["ssm-secure:param3"])
+	for i := 0; i < waiters; i++ {
+		assert.Equal(t, 1, provider.requests["ssm:own"+strconv.Itoa(i)])
+	}
+	assert.Equal(t, 0, len(singleFlight.calls))
+}
+
+func TestSingleFlightProviderSharesMissingParameters(t *testing.T) {
+	provider := newBlockingProviderMock(cacheTestRecords)
+	singleFlight := NewSingleFlightProvider(provider)
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := singleFlight.GetParameters(context.Background(), []string{"ssm:missing"})
+		errs <- err
+	}()
+	<-provider.started
+	go func() {
+		_, err := singleFlight.GetParameters(context.Background(), []string{"ssm:missing", "ssm:param1"})
+		errs <- err
+	}()
+	// the request for ssm:param1 starts after the second lookup joined the call for ssm:missing
+	<-provider.started
+	close(provider.release)
+
+	for i := 0; i < 2; i++ {
+		var missingParametersError *MissingParametersError
+		assert.True(t, errors.As(<-errs, &missingParametersError))
+		assert.Equal(t, []string{"ssm:missing"}, missingParametersError.References)
+	}
+	assert.Equal(t, 1, provider.requests["ssm:missing"])
+}
+
+func TestSingleFlightProviderWaiterSurvivesCancelledLeader(t *testing.T) {
+	provider := newBlockingProviderMock(cacheTestRecords)
+	singleFlight := NewSingleFlightProvider(provider)
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := singleFlight.GetParameters(leaderCtx, []string{"ssm:param1"})
+		leaderErr <- err
+	}()
+	<-provider.started
+
+	waiterResult := make(chan map[string]SsmParameterInfo, 1)
+	go func() {
+		result, err := singleFlight.GetParameters(context.Background(), []string{"ssm:param1", "ssm:param2"})
+		assert.Nil(t, err)
+		waiterResult <- result
+	}()
+	// the waiter joined the call for ssm:param1 before its own request for ssm:param2 started
+	<-provider.started
+
+	cancelLeader()
+	assert.Equal(t, context.Canceled, <-leaderErr)
+
+	close(provider.release)
+	result := <-waiterResult
+	assert.Equal(t, "value_param1", result["ssm:param1"].Value)
+	assert.Equal(t, "value_param2", result["ssm:param2"].Value)
+}
@@ -1,139 +1,238 @@
 package resolver
 
 import (
-	"log"
-	"os"
+	"context"
+	"sort"
+	"sync"
 
 	"errors"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
-	"github.com/aws/aws-sdk-go/aws/ec2metadata"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 )
 
 //
 // Maximum number of parameters that can be requested from SSM Parameter store in one GetParameters request
 const maxParametersRetrievedFromSsm = 10
 
-type ISsmParameterService interface {
-	callGetParameters(parameterReferences []string) (map[string]SsmParameterInfo, error)
-}
-
 type Service struct {
-	SSMClient *ssm.SSM
+	SSMClient   ssmiface.SSMAPI
+	RetryPolicy RetryPolicy
 }
 
-func NewService() (service *Service, err error) {
-	currentSession, err := session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-	})
-	if err != nil {
-		return
-	}
-
-	if *currentSession.Config.Region == "" {
-		log.Println("There is no explict region configuration, retriving ec2metadata...")
-		region, err := ec2metadata.New(currentSession).Region()
-		if err != nil {
-			return nil, err
-		}
-		currentSession.Config.Region = aws.String(region)
-	}
-
-	var client *ssm.SSM
-	if arn := os.Getenv("SSM2ENV_ASSUME_ROLE_ARN"); arn != "" {
-		credentials := stscreds.NewCredentials(currentSession, arn)
-		client = ssm.New(currentSession, &aws.Config{Credentials: credentials})
-	} else {
-		client = ssm.New(currentSession)
-	}
+var _ IPrefixedParameterProvider = (*Service)(nil)
 
-	service = &Service{
-		SSMClient: client,
-	}
+//
+// Creates a Service from the default AWS configuration. The role from the SSM2ENV_ASSUME_ROLE_ARN
+// environment variable is assumed if it is set. Use NewServiceWithOptions for other setups.
+func NewService() (service *Service, err error) {
+	return NewServiceWithOptions(WithAssumeRoleArnFromEnv())
+}
 
-	return
+func (s *Service) ReferencePrefixes() []ReferencePrefix {
+	return append([]ReferencePrefix{}, ssmServiceReferencePrefixes...)
 }
 
 //
 // This function takes a list of at most maxParametersRetrievedFromSsm(=10) ssm parameter name references like (ssm:name).
-// It returns a map<param-ref, SsmParameterInfo>.
-func (s *Service) callGetParameters(parameterReferences []string) (map[string]SsmParameterInfo, error) {
+// It returns a map<param-ref, SsmParameterInfo>. Path references (ssm-path:/a/b/) are listed one by one,
+// list references (ssm-list:name) are fetched with the others and their elements rendered afterwards.
+func (s *Service) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
 
-	name2RefMap := make(map[string]string)
+	// the same name may be referenced with both ssm: and ssm-secure: prefixes.
+	// Keys are names or ARNs (with selector), exactly as sent to SSM.
+	name2RefMap := make(map[string][]string)
+	names := []string{}
+	pathReferences := []string{}
 
 	for i := 0; i < len(parameterReferences); i++ {
-		nameWithoutPrefix := extractParameterNameFromReference(parameterReferences[i])
-		name2RefMap[nameWithoutPrefix] = parameterReferences[i]
-		parameterReferences[i] = nameWithoutPrefix
+		if strings.HasPrefix(parameterReferences[i], ssmPathPrefix) {
+			pathReferences = append(pathReferences, parameterReferences[i])
+			continue
+		}
+
+		parameter := parseParameterReference(parameterReferences[i])
+		if strings.HasPrefix(parameterReferences[i], ssmListPrefix) {
+			parsed, _ := parseParameterListReference(parameterReferences[i])
+			parameter = parsed.parameter
+		}
+
+		nameWithSelector := parameter.nameWithSelector()
+		if _, found := name2RefMap[nameWithSelector]; !found {
+			names = append(names, nameWithSelector)
+		}
+		name2RefMap[nameWithSelector] = append(name2RefMap[nameWithSelector], parameterReferences[i])
 	}
 
-	parametersOutput, err := s.SSMClient.GetParameters(&ssm.GetParametersInput{
-		Names:          aws.StringSlice(parameterReferences),
-		WithDecryption: aws.Bool(true),
-	})
+	resolvedParametersMap, missingReferences, err := s.getParameterPaths(ctx, pathReferences)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(parametersOutput.InvalidParameters) > 0 {
-		invalidParameters := []string{}
-		for _, p := range parametersOutput.InvalidParameters {
-			invalidParameters = append(invalidParameters, *p)
+	parametersOutput := &ssm.GetParametersOutput{}
+	if len(names) > 0 {
+		err = s.RetryPolicy.do(ctx, func() (err error) {
+			parametersOutput, err = s.SSMClient.GetParametersWithContext(ctx, &ssm.GetParametersInput{
+				Names:          aws.StringSlice(names),
+				WithDecryption: aws.Bool(true),
+			})
+			return err
+		})
+		if err != nil {
+			return nil, err
 		}
-		return nil, errors.New("The following parameter(s) cannot be resolved: " + strings.Join(invalidParameters, ","))
 	}
 
-	resolvedParametersMap := map[string]SsmParameterInfo{}
 	for i := 0; i < len(parametersOutput.Parameters); i++ {
 		param := parametersOutput.Parameters[i]
-		resolvedParametersMap[name2RefMap[*param.Name]] = SsmParameterInfo{
-			Name:  *param.Name,
-			Type:  *param.Type,
-			Value: *param.Value,
+		var elements []string
+		if *param.Type == stringListType {
+			elements = splitStringList(*param.Value)
+		}
+		for _, ref := range referencesForResponse(name2RefMap, param) {
+			resolvedParametersMap[ref] = SsmParameterInfo{
+				Name:     *param.Name,
+				Type:     *param.Type,
+				Value:    *param.Value,
+				Version:  aws.Int64Value(param.Version),
+				ARN:      aws.StringValue(param.ARN),
+				Elements: elements,
+			}
 		}
 	}
 
+	if err := renderParameterLists(resolvedParametersMap); err != nil {
+		return nil, err
+	}
+
+	for _, p := range parametersOutput.InvalidParameters {
+		missingReferences = append(missingReferences, name2RefMap[*p]...)
+	}
+	if len(missingReferences) > 0 {
+		return resolvedParametersMap, newMissingParametersError(missingReferences)
+	}
+
 	return resolvedParametersMap, nil
 }
 
 //
-// This function takes as an input a list of references to the SSMParameterService and return a map <reference, SSMParameterInfo>
-func getParametersFromSsmParameterStore(s ISsmParameterService, parametersToFetch []string) (map[string]SsmParameterInfo, error) {
-
-	outputMap := make(map[string]SsmParameterInfo)
+// This function takes as an input a list of references to the IParameterProvider and return a map <reference, SSMParameterInfo>.
+// Batches are requested by at most maxConcurrentRequests goroutines, the first failing batch cancels the rest,
+// and batches that have not been requested yet are skipped once ctx is done.
+// Missing parameters don't stop the remaining batches, they are all reported in one MissingParametersError
+// returned along with the resolved parameters.
+func getParametersFromSsmParameterStore(
+	ctx context.Context,
+	s IParameterProvider,
+	parametersToFetch []string,
+	maxConcurrentRequests int) (map[string]SsmParameterInfo, error) {
+
+	if maxConcurrentRequests < 1 {
+		maxConcurrentRequests = 1
+	}
 
-	var totalParams = len(parametersToFetch)
-	var startPos = 0
-	for totalParams > 0 {
+	// sorted input gives the same batches, and so the same merge order, on every run
+	sortedParameters := append([]string{}, parametersToFetch...)
+	sort.Strings(sortedParameters)
 
-		var paramsBatch []string
+	batches := [][]string{}
+	for start := 0; start < len(sortedParameters); start += maxParametersRetrievedFromSsm {
+		end := start + maxParametersRetrievedFromSsm
+		if end > len(sortedParameters) {
+			end = len(sortedParameters)
+		}
+		batches = append(batches, sortedParameters[start:end])
+	}
 
-		var count = 0
-		for i := startPos; i < len(parametersToFetch) && count < maxParametersRetrievedFromSsm; i++ {
-			paramsBatch = append(paramsBatch, parametersToFetch[i])
+	batchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-			totalParams--
-			count++
-			startPos++
+	type batchResult struct {
+		parameters        map[string]SsmParameterInfo
+		missingReferences []string
+	}
+	results := make([]batchResult, len(batches))
+
+	var firstError error
+	var reportError sync.Once
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, maxConcurrentRequests)
+
+	for i := range batches {
+		semaphore <- struct{}{}
+		if batchCtx.Err() != nil {
+			<-semaphore
+			break
 		}
 
-		results, err := s.callGetParameters(paramsBatch)
-		if err != nil {
-			return nil, err
-		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			parameters, err := s.GetParameters(batchCtx, batches[i])
+
+			var missingParametersError *MissingParametersError
+			if errors.As(err, &missingParametersError) {
+				results[i].missingReferences = missingParametersError.References
+			} else if err != nil {
+				reportError.Do(func() {
+					firstError = err
+					cancel()
+				})
+				return
+			}
+			results[i].parameters = parameters
+		}(i)
+	}
+	wg.Wait()
 
-		for name, value := range results {
+	if firstError != nil {
+		return nil, firstError
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	outputMap := make(map[string]SsmParameterInfo)
+	missingReferences := []string{}
+	for _, result := range results {
+		for name, value := range result.parameters {
 			outputMap[name] = value
 		}
+		missingReferences = append(missingReferences, result.missingReferences...)
+	}
+
+	if len(missingReferences) > 0 {
+		return outputMap, newMissingParametersError(missingReferences)
 	}
 
 	return outputMap, nil
 }
 
+//
+// Finds references a returned parameter answers. Parameters requested by ARN are matched by their ARN only,
+// so a shared parameter never overrides a local parameter with the same name.
+// SSM echoes the requested version or label back in Selector (as ":3" or ":prod").
+func referencesForResponse(name2RefMap map[string][]string, param *ssm.Parameter) []string {
+	selector := strings.TrimPrefix(aws.StringValue(param.Selector), ":")
+	withSelector := func(name string) string {
+		if selector == "" {
+			return name
+		}
+		return name + ":" + selector
+	}
+
+	if param.ARN != nil {
+		if refs, found := name2RefMap[withSelector(*param.ARN)]; found {
+			return refs
+		}
+	}
+	return name2RefMap[withSelector(*param.Name)]
+}
+
 func extractParameterNameFromReference(parameterReference string) string {
 	return parameterReference[strings.Index(parameterReference, ":")+1:]
 }
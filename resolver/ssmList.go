@@ -0,0 +1,143 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//
+// Separator of the elements of a StringList parameter
+const stringListSeparator = ","
+
+//
+// ListFormat is the text form the elements of a StringList parameter are rendered in
+type ListFormat string
+
+const (
+	// JSON array of strings, e.g. ["a","b"]
+	ListFormatJSON ListFormat = "json"
+	// YAML flow sequence, e.g. [a, b]
+	ListFormatYAML ListFormat = "yaml"
+	// Elements joined by a separator, a comma unless told otherwise
+	ListFormatText ListFormat = "text"
+)
+
+//
+// Returns the elements of a StringList value. SSM stores them comma separated, without escaping.
+func splitStringList(value string) []string {
+	return strings.Split(value, stringListSeparator)
+}
+
+//
+// Renders the elements of a StringList parameter as text in the given format.
+// The separator is only used by ListFormatText.
+func RenderStringList(elements []string, format ListFormat, separator string) (string, error) {
+	switch format {
+	case ListFormatJSON, "":
+		var buffer strings.Builder
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(append([]string{}, elements...)); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buffer.String(), "\n"), nil
+
+	case ListFormatYAML:
+		sequence := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, element := range elements {
+			sequence.Content = append(sequence.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: element})
+		}
+		output, err := yaml.Marshal(sequence)
+		return strings.TrimSuffix(string(output), "\n"), err
+
+	case ListFormatText:
+		return strings.Join(elements, separator), nil
+	}
+
+	return "", errors.New("unknown list format " + string(format))
+}
+
+//
+// Parsed form of a list reference like ssm-list:/app/hosts:3?format=text&separator=%20
+type parameterListReference struct {
+	parameter parameterReference
+	format    ListFormat
+	separator string
+}
+
+//
+// Parses a list reference and returns the reason it is invalid, or an empty string.
+// A separator alone selects the text format.
+func parseParameterListReference(reference string) (parameterListReference, string) {
+	body := strings.TrimPrefix(reference, ssmListPrefix)
+	result := parameterListReference{format: ListFormatJSON, separator: stringListSeparator}
+
+	if pos := strings.Index(body, "?"); pos >= 0 {
+		query, err := url.ParseQuery(body[pos+1:])
+		if err != nil {
+			return result, "malformed query: " + err.Error()
+		}
+		body = body[:pos]
+
+		queryKeys := []string{}
+		for key := range query {
+			queryKeys = append(queryKeys, key)
+		}
+		sort.Strings(queryKeys)
+
+		for _, key := range queryKeys {
+			value := query.Get(key)
+			switch key {
+			case "format":
+				result.format = ListFormat(value)
+				if result.format != ListFormatJSON && result.format != ListFormatYAML && result.format != ListFormatText {
+					return result, "format must be json, yaml or text"
+				}
+			case "separator":
+				result.separator = value
+			default:
+				return result, "unknown option " + key
+			}
+		}
+
+		if _, found := query["separator"]; found {
+			if _, found := query["format"]; !found {
+				result.format = ListFormatText
+			} else if result.format != ListFormatText {
+				return result, "separator is only used with format text"
+			}
+		}
+	}
+
+	result.parameter = parseParameterReference(ssmListPrefix + body)
+	return result, result.parameter.validate()
+}
+
+//
+// Replaces the values of list references in resolvedParametersMap by their rendered elements.
+// Values of other types are left as they are, the type check rejects them later.
+func renderParameterLists(resolvedParametersMap map[string]SsmParameterInfo) error {
+	for ref, param := range resolvedParametersMap {
+		if !strings.HasPrefix(ref, ssmListPrefix) || param.Type != stringListType {
+			continue
+		}
+
+		parsed, reason := parseParameterListReference(ref)
+		if reason != "" {
+			return &InvalidParameterReferenceError{References: []InvalidParameterReference{{Reference: ref, Reason: reason}}}
+		}
+
+		value, err := RenderStringList(param.Elements, parsed.format, parsed.separator)
+		if err != nil {
+			return err
+		}
+		param.Value = value
+		resolvedParametersMap[ref] = param
+	}
+	return nil
+}
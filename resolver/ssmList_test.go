@@ -0,0 +1,116 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSsmListClientMock() *ssmClientMock {
+	return &ssmClientMock{parameters: map[string]*ssm.Parameter{
+		"/app/hosts": {Name: aws.String("/app/hosts"), Type: aws.String(stringListType), Value: aws.String(`a.example.com,b "c",true`),
+			Version: aws.Int64(2)},
+		"/app/name": {Name: aws.String("/app/name"), Type: aws.String(stringType), Value: aws.String("demo"), Version: aws.Int64(1)},
+	}}
+}
+
+func TestRenderStringList(t *testing.T) {
+	elements := []string{"a.example.com", `b "c"`, "true"}
+	testCases := []struct {
+		format    ListFormat
+		separator string
+		expected  string
+	}{
+		{ListFormatJSON, "", `["a.example.com","b \"c\"","true"]`},
+		{ListFormatYAML, "", `[a.example.com, b "c", "true"]`},
+		{ListFormatText, " ", `a.example.com b "c" true`},
+	}
+	for _, testCase := range testCases {
+		output, err := RenderStringList(elements, testCase.format, testCase.separator)
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.expected, output)
+	}
+
+	output, err := RenderStringList(nil, ListFormatJSON, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "[]", output)
+
+	_, err = RenderStringList(elements, "csv", "")
+	assert.NotNil(t, err)
+}
+
+func TestParseParameterListReference(t *testing.T) {
+	parsed, reason := parseParameterListReference("ssm-list:/app/hosts:2?separator=%3B%20")
+	assert.Equal(t, "", reason)
+	assert.Equal(t, "/app/hosts:2", parsed.parameter.nameWithSelector())
+	assert.Equal(t, ListFormatText, parsed.format)
+	assert.Equal(t, "; ", parsed.separator)
+
+	parsed, reason = parseParameterListReference("ssm-list:/app/hosts")
+	assert.Equal(t, "", reason)
+	assert.Equal(t, ListFormatJSON, parsed.format)
+
+	for _, reference := range []string{
+		"ssm-list:/app/hosts?format=csv",
+		"ssm-list:/app/hosts?format=json&separator=;",
+		"ssm-list:/app/hosts?sort=true",
+		"ssm-list:bad name",
+	} {
+		_, reason := parseParameterListReference(reference)
+		assert.NotEqual(t, "", reason, reference)
+	}
+}
+
+func TestServiceGetParametersWithLists(t *testing.T) {
+	client := newSsmListClientMock()
+	service := &Service{SSMClient: client}
+
+	parameters, err := service.GetParameters(context.Background(), []string{
+		"ssm:/app/hosts", "ssm-list:/app/hosts", "ssm-list:/app/hosts?format=yaml", "ssm-list:/app/hosts?separator=|",
+	})
+
+	assert.Nil(t, err)
+	// all list references share one request for the parameter
+	assert.Equal(t, [][]string{{"/app/hosts"}}, client.requestedNames)
+
+	elements := []string{"a.example.com", `b "c"`, "true"}
+	assert.Equal(t, `a.example.com,b "c",true`, parameters["ssm:/app/hosts"].Value)
+	assert.Equal(t, elements, parameters["ssm:/app/hosts"].Elements)
+	assert.Equal(t, `["a.example.com","b \"c\"","true"]`, parameters["ssm-list:/app/hosts"].Value)
+	assert.Equal(t, elements, parameters["ssm-list:/app/hosts"].Elements)
+	assert.Equal(t, `[a.example.com, b "c", "true"]`, parameters["ssm-list:/app/hosts?format=yaml"].Value)
+	assert.Equal(t, `a.example.com|b "c"|true`, parameters["ssm-list:/app/hosts?separator=|"].Value)
+}
+
+func TestResolveParametersInTextWithLists(t *testing.T) {
+	service := &Service{SSMClient: newSsmListClientMock()}
+
+	output, err := ResolveParametersInText(service, `{"hosts": {{ssm-list:/app/hosts}}, "name": "{{ssm:/app/name}}"}`, ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"hosts": ["a.example.com","b \"c\"","true"], "name": "demo"}`, output)
+
+	// the list prefix only accepts StringList parameters
+	_, err = ResolveParametersInText(service, "{{ssm-list:/app/name}}", ResolveOptions{})
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, []ParameterTypeMismatch{{Reference: "ssm-list:/app/name", Prefix: ssmListPrefix, Type: stringType}},
+		resolutionError.TypeMismatches)
+	assert.Contains(t, err.Error(), "list prefix ssm-list: is used for a non-list type String")
+
+	_, err = ResolveParametersInText(service, "{{ssm-list:/app/hosts?format=csv}}", ResolveOptions{})
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+}
+
+func TestResolveParametersInYAMLWithLists(t *testing.T) {
+	service := &Service{SSMClient: newSsmListClientMock()}
+
+	output, err := ResolveParametersInYAML(service, "hosts: '{{ssm-list:/app/hosts}}'\n", ResolveOptions{TypedValues: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "hosts:\n  - a.example.com\n  - b \"c\"\n  - \"true\"\n", output)
+}
@@ -0,0 +1,278 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"gopkg.in/yaml.v3"
+)
+
+//
+// Maximum number of parameters SSM returns in one GetParametersByPath page
+const maxParametersRetrievedByPath = 10
+
+//
+// PathFormat is the text form all parameters under a path are rendered in
+type PathFormat string
+
+const (
+	// JSON object of relative name to value, e.g. {"db/host":"localhost"}
+	PathFormatJSON PathFormat = "json"
+	// One KEY="value" line per parameter, the key is the upper-cased relative name, e.g. DB_HOST="localhost"
+	PathFormatDotenv PathFormat = "dotenv"
+	// YAML map of relative name to value
+	PathFormatYAML PathFormat = "yaml"
+)
+
+var dotenvKeyIllegalCharacters = regexp.MustCompile(`[^A-Z0-9_]`)
+var dotenvBareValue = regexp.MustCompile(`^[a-zA-Z0-9_./:@+,\-]*$`)
+
+//
+// GetParametersByPathOptions select the parameters returned by Service.GetParametersByPath
+type GetParametersByPathOptions struct {
+	// Also return parameters in all levels below the path
+	Recursive bool
+	// Passed to SSM as is, e.g. {Key: "Label", Option: "Equals", Values: ["prod"]}
+	ParameterFilters []*ssm.ParameterStringFilter
+}
+
+//
+// Returns all parameters under path, sorted by name. SecureString values are decrypted.
+// Every page is requested with the retry policy of the service.
+func (s *Service) GetParametersByPath(ctx context.Context, path string, options GetParametersByPathOptions) ([]SsmParameterInfo, error) {
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(options.Recursive),
+		WithDecryption: aws.Bool(true),
+		MaxResults:     aws.Int64(maxParametersRetrievedByPath),
+	}
+	if len(options.ParameterFilters) > 0 {
+		input.ParameterFilters = options.ParameterFilters
+	}
+
+	result := []SsmParameterInfo{}
+	for {
+		var output *ssm.GetParametersByPathOutput
+		err := s.RetryPolicy.do(ctx, func() (err error) {
+			output, err = s.SSMClient.GetParametersByPathWithContext(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, param := range output.Parameters {
+			result = append(result, SsmParameterInfo{
+				Name:    aws.StringValue(param.Name),
+				Type:    aws.StringValue(param.Type),
+				Value:   aws.StringValue(param.Value),
+				Version: aws.Int64Value(param.Version),
+				ARN:     aws.StringValue(param.ARN),
+			})
+		}
+
+		if aws.StringValue(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+//
+// Renders parameters returned for path as text in the given format. Parameters are keyed by their name
+// relative to path, or by their full name when they are not under it.
+func RenderParametersByPath(parameters []SsmParameterInfo, path string, format PathFormat) (string, error) {
+	keys := []string{}
+	values := map[string]string{}
+	for _, param := range parameters {
+		key := relativeParameterName(param.Name, path)
+		if format == PathFormatDotenv {
+			key = dotenvKey(key)
+		}
+		if _, found := values[key]; found {
+			return "", errors.New("parameters under " + path + " map to the same key " + key)
+		}
+		keys = append(keys, key)
+		values[key] = param.Value
+	}
+	sort.Strings(keys)
+
+	switch format {
+	case PathFormatJSON, "":
+		output, err := json.Marshal(values)
+		return string(output), err
+
+	case PathFormatDotenv:
+		lines := []string{}
+		for _, key := range keys {
+			lines = append(lines, key+"="+dotenvValue(values[key]))
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case PathFormatYAML:
+		if len(keys) == 0 {
+			return "{}", nil
+		}
+		mapping := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range keys {
+			mapping.Content = append(mapping.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: values[key]})
+		}
+		output, err := yaml.Marshal(mapping)
+		return strings.TrimSuffix(string(output), "\n"), err
+	}
+
+	return "", errors.New("unknown path format " + string(format))
+}
+
+func relativeParameterName(name string, path string) string {
+	basePath := strings.TrimSuffix(path, "/") + "/"
+	if !strings.HasPrefix(name, basePath) {
+		return name
+	}
+	return strings.TrimPrefix(name, basePath)
+}
+
+//
+// Turns a relative parameter name like db/host-name into an environment variable name like DB_HOST_NAME
+func dotenvKey(name string) string {
+	key := dotenvKeyIllegalCharacters.ReplaceAllString(strings.ToUpper(name), "_")
+	if key == "" || (key[0] >= '0' && key[0] <= '9') {
+		key = "_" + key
+	}
+	return key
+}
+
+func dotenvValue(value string) string {
+	if dotenvBareValue.MatchString(value) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, `$`, `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+//
+// Parsed form of a path reference like ssm-path:/app/prod/?recursive=true&format=dotenv&label=prod
+type parameterPathReference struct {
+	path    string
+	format  PathFormat
+	options GetParametersByPathOptions
+}
+
+//
+// Query keys of path references that become parameter filters, GetParametersByPath only accepts these filter keys
+var parameterPathFilterKeys = map[string]string{
+	"keyid": "KeyId",
+	"label": "Label",
+	"type":  "Type",
+}
+
+func parseParameterPathReference(reference string) (parameterPathReference, string) {
+	body := strings.TrimPrefix(reference, ssmPathPrefix)
+	result := parameterPathReference{format: PathFormatJSON}
+
+	if pos := strings.Index(body, "?"); pos >= 0 {
+		query, err := url.ParseQuery(body[pos+1:])
+		if err != nil {
+			return result, "malformed query: " + err.Error()
+		}
+		body = body[:pos]
+
+		queryKeys := []string{}
+		for key := range query {
+			queryKeys = append(queryKeys, key)
+		}
+		sort.Strings(queryKeys)
+
+		for _, key := range queryKeys {
+			value := query.Get(key)
+			switch key {
+			case "recursive":
+				recursive, err := strconv.ParseBool(value)
+				if err != nil {
+					return result, "recursive must be true or false"
+				}
+				result.options.Recursive = recursive
+			case "format":
+				result.format = PathFormat(value)
+				if result.format != PathFormatJSON && result.format != PathFormatDotenv && result.format != PathFormatYAML {
+					return result, "format must be json, dotenv or yaml"
+				}
+			default:
+				filterKey, found := parameterPathFilterKeys[key]
+				if !found {
+					return result, "unknown option " + key
+				}
+				result.options.ParameterFilters = append(result.options.ParameterFilters, &ssm.ParameterStringFilter{
+					Key:    aws.String(filterKey),
+					Option: aws.String("Equals"),
+					Values: aws.StringSlice(query[key]),
+				})
+			}
+		}
+	}
+
+	result.path = body
+	if !strings.HasPrefix(body, "/") {
+		return result, "path must begin with /"
+	}
+	if body != "/" {
+		if reason := validateParameterName(strings.TrimSuffix(body, "/")); reason != "" {
+			return result, reason
+		}
+	}
+
+	return result, ""
+}
+
+//
+// Resolves path references of the service: every reference is one GetParametersByPath listing
+// rendered in the format it asks for. Paths without parameters are reported as missing.
+func (s *Service) getParameterPaths(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, []string, error) {
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+
+	for _, ref := range parameterReferences {
+		parsed, reason := parseParameterPathReference(ref)
+		if reason != "" {
+			return nil, nil, &InvalidParameterReferenceError{References: []InvalidParameterReference{{Reference: ref, Reason: reason}}}
+		}
+
+		parameters, err := s.GetParametersByPath(ctx, parsed.path, parsed.options)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(parameters) == 0 {
+			missingReferences = append(missingReferences, ref)
+			continue
+		}
+
+		value, err := RenderParametersByPath(parameters, parsed.path, parsed.format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// the listing counts as secure as soon as one of its values is
+		parameterType := stringType
+		for _, param := range parameters {
+			if param.Type == secureStringType {
+				parameterType = secureStringType
+			}
+		}
+		result[ref] = SsmParameterInfo{Name: parsed.path, Type: parameterType, Value: value}
+	}
+
+	return result, missingReferences, nil
+}
@@ -0,0 +1,159 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
+)
+
+// Serves the parameters of the mock by path, two per page, and honours a Label filter
+func (m *ssmClientMock) GetParametersByPathWithContext(ctx aws.Context, input *ssm.GetParametersByPathInput, opts ...request.Option) (*ssm.GetParametersByPathOutput, error) {
+	m.requestedPaths = append(m.requestedPaths, input)
+
+	label := ""
+	for _, filter := range input.ParameterFilters {
+		if aws.StringValue(filter.Key) == "Label" {
+			label = aws.StringValue(filter.Values[0])
+		}
+	}
+
+	names := []string{}
+	for name, param := range m.parameters {
+		relativeName := strings.TrimPrefix(name, aws.StringValue(input.Path))
+		if relativeName == name || (!aws.BoolValue(input.Recursive) && strings.Contains(relativeName, "/")) {
+			continue
+		}
+		if label != "" && aws.StringValue(param.Selector) != ":"+label {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	start, _ := strconv.Atoi(aws.StringValue(input.NextToken))
+	output := &ssm.GetParametersByPathOutput{}
+	for i := start; i < len(names) && i < start+2; i++ {
+		output.Parameters = append(output.Parameters, m.parameters[names[i]])
+	}
+	if start+2 < len(names) {
+		output.NextToken = aws.String(strconv.Itoa(start + 2))
+	}
+	return output, nil
+}
+
+func newSsmPathClientMock() *ssmClientMock {
+	parameter := func(name string, parameterType string, value string) *ssm.Parameter {
+		return &ssm.Parameter{Name: aws.String(name), Type: aws.String(parameterType), Value: aws.String(value), Version: aws.Int64(1)}
+	}
+	return &ssmClientMock{parameters: map[string]*ssm.Parameter{
+		"/app/prod/host":        parameter("/app/prod/host", stringType, "db.example.com"),
+		"/app/prod/port":        parameter("/app/prod/port", stringType, "5432"),
+		"/app/prod/db/user":     parameter("/app/prod/db/user", stringType, "admin"),
+		"/app/prod/db/password": parameter("/app/prod/db/password", secureStringType, `p@ss "word"`),
+		"/app/dev/host":         parameter("/app/dev/host", stringType, "localhost"),
+	}}
+}
+
+func TestServiceGetParametersByPath(t *testing.T) {
+	client := newSsmPathClientMock()
+	service := &Service{SSMClient: client}
+
+	parameters, err := service.GetParametersByPath(context.Background(), "/app/prod/", GetParametersByPathOptions{Recursive: true})
+
+	assert.Nil(t, err)
+	names := []string{}
+	for _, param := range parameters {
+		names = append(names, param.Name)
+	}
+	assert.Equal(t, []string{"/app/prod/db/password", "/app/prod/db/user", "/app/prod/host", "/app/prod/port"}, names)
+	// four parameters in pages of two
+	assert.Equal(t, 2, len(client.requestedPaths))
+	assert.True(t, aws.BoolValue(client.requestedPaths[0].WithDecryption))
+
+	parameters, err = service.GetParametersByPath(context.Background(), "/app/prod/", GetParametersByPathOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(parameters))
+}
+
+func TestRenderParametersByPath(t *testing.T) {
+	parameters := []SsmParameterInfo{
+		{Name: "/app/prod/db/password", Value: `p@ss "word"`},
+		{Name: "/app/prod/host", Value: "db.example.com"},
+		{Name: "/app/prod/port", Value: "5432"},
+	}
+
+	output, err := RenderParametersByPath(parameters, "/app/prod/", PathFormatJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"db/password":"p@ss \"word\"","host":"db.example.com","port":"5432"}`, output)
+
+	output, err = RenderParametersByPath(parameters, "/app/prod", PathFormatDotenv)
+	assert.Nil(t, err)
+	assert.Equal(t, "DB_PASSWORD=\"p@ss \\\"word\\\"\"\nHOST=db.example.com\nPORT=5432", output)
+
+	output, err = RenderParametersByPath(parameters, "/app/prod/", PathFormatYAML)
+	assert.Nil(t, err)
+	assert.Equal(t, "db/password: p@ss \"word\"\nhost: db.example.com\nport: \"5432\"", output)
+
+	_, err = RenderParametersByPath([]SsmParameterInfo{{Name: "/a/db-host"}, {Name: "/a/db/host"}}, "/a", PathFormatDotenv)
+	assert.NotNil(t, err)
+}
+
+func TestParseParameterPathReference(t *testing.T) {
+	parsed, reason := parseParameterPathReference("ssm-path:/app/prod/?recursive=true&format=dotenv&label=stable")
+	assert.Equal(t, "", reason)
+	assert.Equal(t, "/app/prod/", parsed.path)
+	assert.Equal(t, PathFormatDotenv, parsed.format)
+	assert.True(t, parsed.options.Recursive)
+	assert.Equal(t, "Label", aws.StringValue(parsed.options.ParameterFilters[0].Key))
+	assert.Equal(t, []string{"stable"}, aws.StringValueSlice(parsed.options.ParameterFilters[0].Values))
+
+	parsed, reason = parseParameterPathReference("ssm-path:/app/prod/?type=SecureString&keyid=alias/app")
+	assert.Equal(t, "", reason)
+	assert.Equal(t, "KeyId", aws.StringValue(parsed.options.ParameterFilters[0].Key))
+	assert.Equal(t, []string{"alias/app"}, aws.StringValueSlice(parsed.options.ParameterFilters[0].Values))
+	assert.Equal(t, "Type", aws.StringValue(parsed.options.ParameterFilters[1].Key))
+
+	for _, reference := range []string{
+		"ssm-path:app/prod/",
+		"ssm-path:/app//prod/",
+		"ssm-path:/app/prod/?format=xml",
+		"ssm-path:/app/prod/?recursive=maybe",
+		"ssm-path:/app/prod/?owner=me",
+		"ssm-path:/app/prod/?tier=Standard",
+	} {
+		_, reason := parseParameterPathReference(reference)
+		assert.NotEqual(t, "", reason, reference)
+	}
+}
+
+func TestResolveParametersInTextWithPathReferences(t *testing.T) {
+	service := &Service{SSMClient: newSsmPathClientMock()}
+
+	output, err := ResolveParametersInText(service,
+		"config={{ssm-path:/app/prod/}}\n{{ ssm-path:/app/dev/?format=dotenv }}\nhost={{ssm:/app/prod/host}}", ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "config={\"host\":\"db.example.com\",\"port\":\"5432\"}\nHOST=localhost\nhost=db.example.com", output)
+
+	_, err = ResolveParametersInText(service, "{{ssm-path:/app/none/}}", ResolveOptions{})
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, []string{"ssm-path:/app/none/"}, resolutionError.MissingReferences)
+
+	_, err = ResolveParametersInText(service, "{{ssm-path:/app/prod/?format=xml}}", ResolveOptions{})
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+
+	// listings may hold secrets, they are left alone when secure parameters are ignored
+	output, err = ResolveParametersInText(service, "{{ssm-path:/app/prod/}}", ResolveOptions{IgnoreSecureParameters: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "{{ssm-path:/app/prod/}}", output)
+}
@@ -1,16 +1,24 @@
 package resolver
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
 	"github.com/stretchr/testify/assert"
 )
 
 type ServiceMockedObjectWithRecords struct {
-	ISsmParameterService
 	records map[string]SsmParameterInfo
 }
 
@@ -21,22 +29,111 @@ func NewServiceMockedObjectWithExtraRecords(
 	}
 }
 
-func (m *ServiceMockedObjectWithRecords) callGetParameters(parameterReferences []string) (map[string]SsmParameterInfo, error) {
+func (m *ServiceMockedObjectWithRecords) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
 	parameters := make(map[string]SsmParameterInfo)
+	missingReferences := []string{}
 
 	for i := 0; i < len(parameterReferences); i++ {
 
 		value, contains := m.records[parameterReferences[i]]
 		if !contains {
-			return nil, errors.New("error: " + parameterReferences[i] + " cannot be resolved")
+			missingReferences = append(missingReferences, parameterReferences[i])
+			continue
 		}
 
 		parameters[parameterReferences[i]] = value
 	}
 
+	if len(missingReferences) > 0 {
+		return parameters, newMissingParametersError(missingReferences)
+	}
+
 	return parameters, nil
 }
 
+//
+// Fake SSM client that serves parameters keyed by name or name:selector
+type ssmClientMock struct {
+	ssmiface.SSMAPI
+	parameters     map[string]*ssm.Parameter
+	requestedNames [][]string
+	requestedPaths []*ssm.GetParametersByPathInput
+}
+
+func (m *ssmClientMock) GetParametersWithContext(ctx aws.Context, input *ssm.GetParametersInput, opts ...request.Option) (*ssm.GetParametersOutput, error) {
+	m.requestedNames = append(m.requestedNames, aws.StringValueSlice(input.Names))
+
+	output := &ssm.GetParametersOutput{}
+	for _, name := range input.Names {
+		if param, found := m.parameters[*name]; found {
+			output.Parameters = append(output.Parameters, param)
+		} else {
+			output.InvalidParameters = append(output.InvalidParameters, name)
+		}
+	}
+	return output, nil
+}
+
+func TestServiceGetParametersWithVersionAndLabel(t *testing.T) {
+	client := &ssmClientMock{parameters: map[string]*ssm.Parameter{
+		"/a/b": {Name: aws.String("/a/b"), Type: aws.String(stringType), Value: aws.String("latest"), Version: aws.Int64(5)},
+		"/a/b:3": {Name: aws.String("/a/b"), Type: aws.String(stringType), Value: aws.String("v3"), Version: aws.Int64(3),
+			Selector: aws.String(":3")},
+		"/a/b:prod": {Name: aws.String("/a/b"), Type: aws.String(stringType), Value: aws.String("v4"), Version: aws.Int64(4),
+			Selector: aws.String(":prod")},
+	}}
+	service := &Service{SSMClient: client}
+
+	result, err := service.GetParameters(context.Background(), []string{"ssm:/a/b", "ssm:/a/b:3", "ssm:/a/b:prod"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/a/b", "/a/b:3", "/a/b:prod"}, client.requestedNames[0])
+	assert.Equal(t, map[string]SsmParameterInfo{
+		"ssm:/a/b":      {Name: "/a/b", Type: stringType, Value: "latest", Version: 5},
+		"ssm:/a/b:3":    {Name: "/a/b", Type: stringType, Value: "v3", Version: 3},
+		"ssm:/a/b:prod": {Name: "/a/b", Type: stringType, Value: "v4", Version: 4},
+	}, result)
+}
+
+func TestServiceGetParametersSameNameWithBothPrefixes(t *testing.T) {
+	client := &ssmClientMock{parameters: map[string]*ssm.Parameter{
+		"param": {Name: aws.String("param"), Type: aws.String(secureStringType), Value: aws.String("value"), Version: aws.Int64(1)},
+	}}
+	service := &Service{SSMClient: client}
+
+	result, err := service.GetParameters(context.Background(), []string{"ssm:param", "ssm-secure:param"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"param"}, client.requestedNames[0])
+	assert.Equal(t, 2, len(result))
+}
+
+func TestServiceGetParametersByArn(t *testing.T) {
+	sharedArn := "arn:aws:ssm:us-east-1:123456789012:parameter/a/b"
+	client := &ssmClientMock{parameters: map[string]*ssm.Parameter{
+		"/a/b": {Name: aws.String("/a/b"), Type: aws.String(stringType), Value: aws.String("local"),
+			ARN: aws.String("arn:aws:ssm:us-east-1:111111111111:parameter/a/b")},
+		sharedArn: {Name: aws.String("/a/b"), Type: aws.String(stringType), Value: aws.String("shared"),
+			ARN: aws.String(sharedArn)},
+		sharedArn + ":2": {Name: aws.String("/a/b"), Type: aws.String(stringType), Value: aws.String("shared_v2"),
+			ARN: aws.String(sharedArn), Selector: aws.String(":2"), Version: aws.Int64(2)},
+	}}
+	service := &Service{SSMClient: client}
+
+	result, err := service.GetParameters(context.Background(), []string{
+		"ssm:/a/b",
+		"ssm:" + sharedArn,
+		"ssm-secure:" + sharedArn + ":2",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/a/b", sharedArn, sharedArn + ":2"}, client.requestedNames[0])
+	assert.Equal(t, "local", result["ssm:/a/b"].Value)
+	assert.Equal(t, "shared", result["ssm:"+sharedArn].Value)
+	assert.Equal(t, "shared_v2", result["ssm-secure:"+sharedArn+":2"].Value)
+	assert.Equal(t, sharedArn, result["ssm-secure:"+sharedArn+":2"].ARN)
+}
+
 func TestGetParametersFromSsmParameterStoreWithAllResolvedNoPaging(t *testing.T) {
 	parametersList := []string{}
 	expectedValues := map[string]SsmParameterInfo{}
@@ -56,7 +153,7 @@ func TestGetParametersFromSsmParameterStoreWithAllResolvedNoPaging(t *testing.T)
 	serviceObject := NewServiceMockedObjectWithExtraRecords(expectedValues)
 
 	t.Log("Testing getParametersFromSsmParameterStore API for all parameters present without paging...")
-	retrievedValues, err := getParametersFromSsmParameterStore(&serviceObject, parametersList)
+	retrievedValues, err := getParametersFromSsmParameterStore(context.Background(), &serviceObject, parametersList, 1)
 	assert.Nil(t, err)
 	assert.True(t, reflect.DeepEqual(expectedValues, retrievedValues))
 }
@@ -80,7 +177,7 @@ func TestGetParametersFromSsmParameterStoreWithAllResolvedWithPaging(t *testing.
 	serviceObject := NewServiceMockedObjectWithExtraRecords(expectedValues)
 
 	t.Log("Testing getParametersFromSsmParameterStore API for all parameters present with paging...")
-	retrievedValues, err := getParametersFromSsmParameterStore(&serviceObject, parametersList)
+	retrievedValues, err := getParametersFromSsmParameterStore(context.Background(), &serviceObject, parametersList, 1)
 	assert.Nil(t, err)
 	assert.True(t, reflect.DeepEqual(expectedValues, retrievedValues))
 }
@@ -95,6 +192,119 @@ func TestGetParametersFromSsmParameterStoreWithUnresolvedIgnoreNoPaging(t *testi
 	serviceObject := NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{})
 
 	t.Log("Testing getParametersFromSsmParameterStore API for all unresolved parameters...")
-	_, err := getParametersFromSsmParameterStore(&serviceObject, parametersList)
+	_, err := getParametersFromSsmParameterStore(context.Background(), &serviceObject, parametersList, 1)
 	assert.NotNil(t, err)
 }
+
+func TestGetParametersFromSsmParameterStoreStopsPendingBatchesOnCancel(t *testing.T) {
+	parametersList := []string{}
+	for i := 0; i < maxParametersRetrievedFromSsm*3; i++ {
+		parametersList = append(parametersList, ssmNonSecurePrefix+"name_"+strconv.Itoa(i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	provider := ParameterProviderFunc(func(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+		calls++
+		cancel()
+		return map[string]SsmParameterInfo{}, nil
+	})
+
+	_, err := getParametersFromSsmParameterStore(ctx, provider, parametersList, 1)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestGetParametersFromSsmParameterStoreReportsMissingFromAllBatches(t *testing.T) {
+	parametersList := []string{}
+	records := map[string]SsmParameterInfo{}
+	for i := 0; i < maxParametersRetrievedFromSsm*3; i++ {
+		key := ssmNonSecurePrefix + fmt.Sprintf("name_%02d", i)
+		parametersList = append(parametersList, key)
+		if i%maxParametersRetrievedFromSsm != 0 {
+			records[key] = SsmParameterInfo{Name: key, Type: stringType, Value: "value"}
+		}
+	}
+
+	serviceObject := NewServiceMockedObjectWithExtraRecords(records)
+
+	retrievedValues, err := getParametersFromSsmParameterStore(context.Background(), &serviceObject, parametersList, 1)
+
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, []string{"ssm:name_00", "ssm:name_10", "ssm:name_20"}, missingParametersError.References)
+	assert.Equal(t, len(records), len(retrievedValues))
+}
+
+func TestServiceGetParametersReportsMissingReferences(t *testing.T) {
+	client := &ssmClientMock{parameters: map[string]*ssm.Parameter{
+		"/a/b": {Name: aws.String("/a/b"), Type: aws.String(stringType), Value: aws.String("value")},
+	}}
+	service := &Service{SSMClient: client}
+
+	result, err := service.GetParameters(context.Background(), []string{"ssm:/a/b", "ssm:/a/c:2", "ssm-secure:/a/c:2"})
+
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, []string{"ssm-secure:/a/c:2", "ssm:/a/c:2"}, missingParametersError.References)
+	assert.Equal(t, "value", result["ssm:/a/b"].Value)
+}
+
+func TestGetParametersFromSsmParameterStoreConcurrently(t *testing.T) {
+	parametersList := []string{}
+	expectedValues := map[string]SsmParameterInfo{}
+	for i := 0; i < maxParametersRetrievedFromSsm*8; i++ {
+		key := ssmNonSecurePrefix + "name_" + strconv.Itoa(i)
+		parametersList = append(parametersList, key)
+		expectedValues[key] = SsmParameterInfo{Name: key, Type: stringType, Value: "value_" + key}
+	}
+	serviceObject := NewServiceMockedObjectWithExtraRecords(expectedValues)
+
+	var inFlight, maxInFlight int32
+	provider := ParameterProviderFunc(func(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return serviceObject.GetParameters(ctx, parameterReferences)
+	})
+
+	retrievedValues, err := getParametersFromSsmParameterStore(context.Background(), provider, parametersList, 3)
+
+	assert.Nil(t, err)
+	assert.True(t, reflect.DeepEqual(expectedValues, retrievedValues))
+	assert.True(t, maxInFlight <= 3)
+	assert.True(t, maxInFlight > 1)
+}
+
+func TestGetParametersFromSsmParameterStoreConcurrentlyCancelsOnFirstError(t *testing.T) {
+	parametersList := []string{}
+	for i := 0; i < maxParametersRetrievedFromSsm*10; i++ {
+		parametersList = append(parametersList, ssmNonSecurePrefix+fmt.Sprintf("name_%03d", i))
+	}
+
+	fatalError := errors.New("access denied")
+	var mutex sync.Mutex
+	requestedBatches := 0
+	provider := ParameterProviderFunc(func(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+		mutex.Lock()
+		requestedBatches++
+		mutex.Unlock()
+
+		if parameterReferences[0] == "ssm:name_000" {
+			return nil, fatalError
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	_, err := getParametersFromSsmParameterStore(context.Background(), provider, parametersList, 2)
+
+	assert.Equal(t, fatalError, err)
+	assert.True(t, requestedBatches < 10)
+}
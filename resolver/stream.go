@@ -0,0 +1,212 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+//
+// Size of the reads from the input of ResolveParametersInStream
+const streamBufferSize = 64 * 1024
+
+//
+// Longest placeholder ResolveParametersInStream recognizes across reads. Longer text between {{ and }}
+// is passed through as is, so a stray {{ doesn't make the whole rest of the input stay in memory.
+const maxStreamedPlaceholderLength = 64 * 1024
+
+//
+// Reads a document from input, resolves the parameters in it according to ResolveOptions and writes
+// the resolved document to output. Only the segment being resolved and the resolved parameters are
+// kept in memory. Nothing is written when a parameter can't be resolved.
+//
+// The input is read twice: seekable inputs like files are rewound, other inputs are spooled to
+// a temporary file first. Document formats need the whole document and aren't supported here.
+// Errors carry the positions of the placeholders in the stream, counted from where reading started.
+func ResolveParametersInStream(
+	service IParameterProvider,
+	input io.Reader,
+	output io.Writer,
+	options ResolveOptions) error {
+
+	return ResolveParametersInStreamWithContext(context.Background(), service, input, output, options)
+}
+
+//
+// Same as ResolveParametersInStream, but SSM requests are bound to ctx.
+func ResolveParametersInStreamWithContext(
+	ctx context.Context,
+	service IParameterProvider,
+	input io.Reader,
+	output io.Writer,
+	options ResolveOptions) error {
+
+	if options.DocumentFormat != DocumentFormatNone {
+		return errors.New("document format " + string(options.DocumentFormat) + " is not supported for streams")
+	}
+
+	rewindableInput, cleanup, err := rewindable(input)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// the placeholders of a segment are found once per pass, keeping them all would make memory grow
+	// with the document instead of with the number of references
+	resolver := newDocumentResolver(service, options)
+	if err := forEachStreamSegment(rewindableInput, func(segment string, origin PlaceholderPosition) error {
+		tokens := resolver.scanner.tokenize(segment)
+		resolver.recordPositions(segment, tokens, origin)
+		return resolver.collectTokens(tokens)
+	}); err != nil {
+		return err
+	}
+	if err := resolver.resolve(ctx); err != nil {
+		return err
+	}
+
+	if err := rewindableInput.rewind(); err != nil {
+		return err
+	}
+	return forEachStreamSegment(rewindableInput, func(segment string, origin PlaceholderPosition) error {
+		tokens := resolver.scanner.tokenize(segment)
+		resolvedSegment, err := resolver.scanner.replaceTokens(segment, tokens, resolver.resolved, DocumentFormatNone)
+		var filterError *PlaceholderFilterError
+		if errors.As(err, &filterError) {
+			filterError.Position = filterError.Position.within(origin)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(output, resolvedSegment)
+		return err
+	})
+}
+
+//
+// Reader that can go back to where reading started
+type rewindableReader struct {
+	io.ReadSeeker
+	start int64
+}
+
+func (r *rewindableReader) rewind() error {
+	_, err := r.Seek(r.start, io.SeekStart)
+	return err
+}
+
+//
+// Returns input as a reader that can be rewound to its current position.
+// Inputs that can't seek, like pipes, are copied to a temporary file that cleanup removes.
+func rewindable(input io.Reader) (*rewindableReader, func(), error) {
+	if seeker, ok := input.(io.ReadSeeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			return &rewindableReader{ReadSeeker: seeker, start: start}, func() {}, nil
+		}
+	}
+
+	spool, err := os.CreateTemp("", "parameter-resolver-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+
+	if _, err := io.Copy(spool, input); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return &rewindableReader{ReadSeeker: spool}, cleanup, nil
+}
+
+//
+// Reads input and calls visit with consecutive segments of it and the position each segment starts at.
+// A segment never ends inside a placeholder of at most maxStreamedPlaceholderLength bytes, nor inside
+// a UTF-8 character.
+func forEachStreamSegment(input io.Reader, visit func(segment string, origin PlaceholderPosition) error) error {
+	buffer := make([]byte, streamBufferSize)
+	pending := []byte{}
+	origin := documentStart
+
+	for {
+		n, err := input.Read(buffer)
+		pending = append(pending, buffer[:n]...)
+
+		if err == io.EOF {
+			if len(pending) == 0 {
+				return nil
+			}
+			return visit(string(pending), origin)
+		} else if err != nil {
+			return err
+		}
+
+		if cut := characterSafeCut(pending, placeholderSafeCut(pending)); cut > 0 {
+			segment := string(pending[:cut])
+			if err := visit(segment, origin); err != nil {
+				return err
+			}
+			origin = positionAfter(origin, segment)
+			pending = append(pending[:0], pending[cut:]...)
+		}
+	}
+}
+
+//
+// Moves cut back to the start of the UTF-8 character it would split, so columns are counted in characters
+func characterSafeCut(text []byte, cut int) int {
+	for start := cut - 1; start >= 0 && start > cut-utf8.UTFMax; start-- {
+		if utf8.RuneStart(text[start]) {
+			if !utf8.FullRune(text[start:cut]) {
+				return start
+			}
+			break
+		}
+	}
+	return cut
+}
+
+//
+// Returns the length of the longest prefix of text that can be resolved on its own, i.e. text up to
+// a placeholder that may continue in the next read. Placeholder bodies end at the first brace outside
+// their quoted default, so only a {{ whose body runs to the end of text (or a { at the very end) is held back.
+func placeholderSafeCut(buffer []byte) int {
+	text := string(buffer)
+	for position := 0; ; {
+		offset := strings.Index(text[position:], placeholderOpening)
+		if offset < 0 {
+			break
+		}
+		start := position + offset
+		bodyStart := start + len(placeholderOpening)
+
+		bodyLength, unterminated := placeholderBodyLength(text[bodyStart:])
+		if bodyLength < 0 || unterminated || bodyStart+bodyLength == len(text)-1 {
+			// the body, or the first brace of its closing }}, is still to come
+			if len(text)-start > maxStreamedPlaceholderLength {
+				return len(text)
+			}
+			return start
+		}
+
+		position = start + 1
+		if strings.HasPrefix(text[bodyStart+bodyLength:], placeholderClosing) {
+			position = bodyStart + bodyLength + len(placeholderClosing)
+		}
+	}
+
+	if strings.HasSuffix(text, "{") {
+		// the first brace of an opening {{
+		return len(text) - 1
+	}
+	return len(text)
+}
@@ -0,0 +1,141 @@
+package resolver
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStreamServiceMock() ServiceMockedObjectWithRecords {
+	records := map[string]SsmParameterInfo{}
+	for i := 0; i < 50; i++ {
+		name := "/app/param" + strconv.Itoa(i)
+		records["ssm:"+name] = SsmParameterInfo{Name: name, Type: stringType, Value: "value-" + strconv.Itoa(i)}
+	}
+	return NewServiceMockedObjectWithExtraRecords(records)
+}
+
+// Document of several read buffers with placeholders at every offset modulo 7
+func newStreamDocument() string {
+	var document strings.Builder
+	for i := 0; document.Len() < 3*streamBufferSize; i++ {
+		document.WriteString(strings.Repeat("x", i%7) + "{{ ssm:/app/param" + strconv.Itoa(i%50) + " }}")
+		if i%11 == 0 {
+			document.WriteString(`{{ssm:/app/missing || "{fall}}back"}} {{ssm:/app/param1 | base64}} {not a placeholder} }}{{`)
+		}
+		document.WriteString("\n")
+	}
+	return document.String()
+}
+
+func TestResolveParametersInStream(t *testing.T) {
+	serviceObject := newStreamServiceMock()
+	document := newStreamDocument()
+	expected, err := ResolveParametersInText(&serviceObject, document, ResolveOptions{})
+	assert.Nil(t, err)
+
+	// strings.Reader is rewound, OneByteReader and HalfReader can't seek and are spooled
+	for name, input := range map[string]io.Reader{
+		"seekable":   strings.NewReader(document),
+		"one byte":   iotest.OneByteReader(strings.NewReader(document)),
+		"half reads": iotest.HalfReader(strings.NewReader(document)),
+	} {
+		var output bytes.Buffer
+		assert.Nil(t, ResolveParametersInStream(&serviceObject, input, &output, ResolveOptions{}), name)
+		assert.Equal(t, expected, output.String(), name)
+	}
+}
+
+func TestResolveParametersInStreamFromCurrentPosition(t *testing.T) {
+	serviceObject := newStreamServiceMock()
+	input := strings.NewReader("header {{ssm:/app/param1}}\nbody {{ssm:/app/param2}}")
+	_, err := input.Read(make([]byte, len("header {{ssm:/app/param1}}\n")))
+	assert.Nil(t, err)
+
+	var output bytes.Buffer
+	err = ResolveParametersInStream(&serviceObject, input, &output, ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "body value-2", output.String())
+}
+
+func TestResolveParametersInStreamErrors(t *testing.T) {
+	serviceObject := newStreamServiceMock()
+
+	var output bytes.Buffer
+	err := ResolveParametersInStream(&serviceObject, strings.NewReader(newStreamDocument()+"{{ssm:/app/missing}}"), &output, ResolveOptions{})
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, 0, output.Len())
+
+	err = ResolveParametersInStream(&serviceObject, strings.NewReader("{}"), &output, ResolveOptions{DocumentFormat: DocumentFormatJSON})
+	assert.NotNil(t, err)
+
+	err = ResolveParametersInStream(&serviceObject, iotest.ErrReader(errors.New("read failed")), &output, ResolveOptions{})
+	assert.EqualError(t, err, "read failed")
+}
+
+func TestResolveParametersInStreamErrorsCarryPositions(t *testing.T) {
+	serviceObject := newStreamServiceMock()
+	// multi-byte characters make byte offsets and columns differ, and may be split between reads
+	document := strings.Repeat("é", 3*streamBufferSize/2) + "\n" + newStreamDocument() +
+		"é {{ssm:/app/other}}\néé {{ssm:/app/param1 | base64decode}}"
+
+	_, textErr := ResolveParametersInText(&serviceObject, document, ResolveOptions{})
+	var textResolutionError *ParameterResolutionError
+	assert.True(t, errors.As(textErr, &textResolutionError))
+
+	for name, input := range map[string]io.Reader{
+		"seekable":   strings.NewReader(document),
+		"half reads": iotest.HalfReader(strings.NewReader(document)),
+	} {
+		err := ResolveParametersInStream(&serviceObject, input, io.Discard, ResolveOptions{})
+		var resolutionError *ParameterResolutionError
+		assert.True(t, errors.As(err, &resolutionError), name)
+		assert.Equal(t, textResolutionError.Positions, resolutionError.Positions, name)
+		assert.Equal(t, textErr.Error(), err.Error(), name)
+	}
+
+	document = strings.Replace(document, "{{ssm:/app/other}}", "", 1)
+	_, textErr = ResolveParametersInText(&serviceObject, document, ResolveOptions{})
+	var textFilterError *PlaceholderFilterError
+	assert.True(t, errors.As(textErr, &textFilterError))
+	assert.True(t, textFilterError.Position.Offset > streamBufferSize)
+
+	err := ResolveParametersInStream(&serviceObject, iotest.HalfReader(strings.NewReader(document)), io.Discard, ResolveOptions{})
+	var filterError *PlaceholderFilterError
+	assert.True(t, errors.As(err, &filterError))
+	assert.Equal(t, textFilterError.Position, filterError.Position)
+}
+
+func TestPlaceholderSafeCut(t *testing.T) {
+	testCases := map[string]int{
+		"no braces":                 9,
+		"done {{ssm:/a}}":           15,
+		"open {{ssm:/a":             5,
+		"half closed {{ssm:/a}":     12,
+		"brace at the end {":        17,
+		"braces {{":                 7,
+		"{single} brace":            14,
+		"text {x} }":                10,
+		"triple {{{ssm:/a":          8,
+		"stray {{ then {x}":         17,
+		"nested {{ {{ssm:/a b":      10,
+		"closed then open }} {{x":   20,
+		`quoted {{ssm:/a || "{}`:    7,
+		`quoted {{ssm:/a || "}}"}}`: 25,
+		`quoted {{ssm:/a || "{}"}`:  7,
+	}
+	for text, expected := range testCases {
+		assert.Equal(t, expected, placeholderSafeCut([]byte(text)), text)
+	}
+
+	tooLong := []byte("{{" + strings.Repeat("a", maxStreamedPlaceholderLength))
+	assert.Equal(t, len(tooLong), placeholderSafeCut(tooLong))
+}
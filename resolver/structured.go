@@ -0,0 +1,579 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+//
+// Takes a JSON document, resolves the placeholders in its string values (and keys with ResolveOptions.ResolveKeys)
+// and returns the resolved document, indented by two spaces with the keys in their original order.
+// With ResolveOptions.TypedValues a string that is exactly one placeholder becomes a number, a boolean
+// or, for StringList parameters, an array of strings. Resolution errors carry the positions of the
+// placeholders in input.
+func ResolveParametersInJSON(
+	service IParameterProvider,
+	input string,
+	options ResolveOptions) (string, error) {
+
+	return ResolveParametersInJSONWithContext(context.Background(), service, input, options)
+}
+
+//
+// Same as ResolveParametersInJSON, but requests are bound to ctx.
+func ResolveParametersInJSONWithContext(
+	ctx context.Context,
+	service IParameterProvider,
+	input string,
+	options ResolveOptions) (string, error) {
+
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	document, err := decodeOrderedJSON(decoder)
+	if err != nil {
+		return "", err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return "", errors.New("unexpected data after the JSON document")
+	}
+
+	resolver := newDocumentResolver(service, options)
+	resolver.recordPositions(input, resolver.scanner.tokenize(input), documentStart)
+	if _, err := walkJSON(document, func(text string, isKey bool) (interface{}, error) {
+		return text, resolver.collect(text, isKey)
+	}); err != nil {
+		return "", err
+	}
+	if err := resolver.resolve(ctx); err != nil {
+		return "", err
+	}
+
+	document, err = walkJSON(document, func(text string, isKey bool) (interface{}, error) {
+		leaf, err := resolver.resolveLeaf(text, isKey)
+		if err != nil || leaf.kind == stringLeaf {
+			return leaf.text, err
+		}
+		switch leaf.kind {
+		case numberLeaf:
+			return json.Number(leaf.text), nil
+		case boolLeaf:
+			return leaf.text == "true", nil
+		}
+		list := []interface{}{}
+		for _, element := range leaf.list {
+			list = append(list, element)
+		}
+		return list, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buffer.String(), "\n"), nil
+}
+
+//
+// Takes a YAML document (or a stream of documents), resolves the placeholders in its string scalars
+// (and keys with ResolveOptions.ResolveKeys) and returns the resolved document. Key order and comments
+// are kept, the document is indented by two spaces. With ResolveOptions.TypedValues a scalar that is exactly
+// one placeholder becomes an integer, float, boolean or, for StringList parameters, a sequence of strings.
+// Like for JSON, resolution errors carry the positions of the placeholders in input.
+func ResolveParametersInYAML(
+	service IParameterProvider,
+	input string,
+	options ResolveOptions) (string, error) {
+
+	return ResolveParametersInYAMLWithContext(context.Background(), service, input, options)
+}
+
+//
+// Same as ResolveParametersInYAML, but requests are bound to ctx.
+func ResolveParametersInYAMLWithContext(
+	ctx context.Context,
+	service IParameterProvider,
+	input string,
+	options ResolveOptions) (string, error) {
+
+	documents := []*yaml.Node{}
+	decoder := yaml.NewDecoder(strings.NewReader(input))
+	for {
+		document := &yaml.Node{}
+		if err := decoder.Decode(document); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		documents = append(documents, document)
+	}
+
+	resolver := newDocumentResolver(service, options)
+	resolver.recordPositions(input, resolver.scanner.tokenize(input), documentStart)
+	lineOffsets := yamlLineOffsets(input)
+	for _, document := range documents {
+		restoreUnquotedPlaceholders(document, input, lineOffsets, resolver.scanner)
+		if err := walkYAML(document, false, func(node *yaml.Node, isKey bool) error {
+			return resolver.collect(node.Value, isKey)
+		}); err != nil {
+			return "", err
+		}
+	}
+	if err := resolver.resolve(ctx); err != nil {
+		return "", err
+	}
+
+	for _, document := range documents {
+		if err := walkYAML(document, false, func(node *yaml.Node, isKey bool) error {
+			leaf, err := resolver.resolveLeaf(node.Value, isKey)
+			if err != nil {
+				return err
+			}
+			setYAMLLeaf(node, leaf)
+			return nil
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+type leafKind int
+
+const (
+	stringLeaf leafKind = iota
+	numberLeaf
+	boolLeaf
+	listLeaf
+)
+
+//
+// A resolved string leaf, possibly turned into a typed value
+type resolvedLeaf struct {
+	kind leafKind
+	text string
+	list []string
+}
+
+//
+// Resolves the placeholders of a document that is passed in pieces (string leaves, stream segments)
+// with one round of requests: pieces are collected first, then resolved, then substituted.
+type documentResolver struct {
+	service IParameterProvider
+	options ResolveOptions
+	scanner *placeholderScanner
+
+	references map[string]bool
+	// references with a default in every placeholder they appear in
+	optionalReferences map[string]bool
+	invalidReferences  []InvalidParameterReference
+	resolved           map[string]SsmParameterInfo
+
+	// placeholders of the leaves passed to collect, in order; resolveLeaf takes the leaves in the same order
+	leafTokens [][]placeholderToken
+	nextLeaf   int
+
+	// where the placeholders of every reference appear, for the errors of resolve
+	positions map[string][]PlaceholderPosition
+}
+
+//
+// Most positions documentResolver keeps per reference, so a stream with many placeholders
+// doesn't make memory grow with its length
+const maxPositionsPerReference = 100
+
+//
+// Position of the first character of a document
+var documentStart = PlaceholderPosition{Line: 1, Column: 1}
+
+func newDocumentResolver(service IParameterProvider, options ResolveOptions) *documentResolver {
+	return &documentResolver{
+		service:            service,
+		options:            options,
+		scanner:            newPlaceholderScanner(referencePrefixesOf(service), options),
+		references:         map[string]bool{},
+		optionalReferences: map[string]bool{},
+		positions:          map[string][]PlaceholderPosition{},
+	}
+}
+
+//
+// Records the positions of placeholders found in text by tokenize, text starts at origin in the document
+func (r *documentResolver) recordPositions(text string, tokens []placeholderToken, origin PlaceholderPosition) {
+	for reference, positions := range placeholderPositionsByReference(text, tokens, "") {
+		for _, position := range positions {
+			if len(r.positions[reference]) < maxPositionsPerReference {
+				r.positions[reference] = append(r.positions[reference], position.within(origin))
+			}
+		}
+	}
+}
+
+//
+// Finds the placeholders of a leaf and records their references. The placeholders are kept for resolveLeaf.
+func (r *documentResolver) collect(text string, isKey bool) error {
+	if isKey && !r.options.ResolveKeys {
+		return nil
+	}
+
+	tokens := r.scanner.tokenize(text)
+	r.leafTokens = append(r.leafTokens, tokens)
+	return r.collectTokens(tokens)
+}
+
+//
+// Records the references of placeholders found by tokenize
+func (r *documentResolver) collectTokens(tokens []placeholderToken) error {
+	references, optionalReferences, err := r.scanner.scanTokens(tokens)
+	var invalidReferenceError *InvalidParameterReferenceError
+	if errors.As(err, &invalidReferenceError) {
+		r.invalidReferences = append(r.invalidReferences, invalidReferenceError.References...)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, ref := range references {
+		if r.references[ref] {
+			r.optionalReferences[ref] = r.optionalReferences[ref] && optionalReferences[ref]
+		} else {
+			r.optionalReferences[ref] = optionalReferences[ref]
+		}
+		r.references[ref] = true
+	}
+	return nil
+}
+
+func (r *documentResolver) resolve(ctx context.Context) error {
+	if len(r.invalidReferences) > 0 {
+		sort.Slice(r.invalidReferences, func(i, j int) bool { return r.invalidReferences[i].Reference < r.invalidReferences[j].Reference })
+		return withReferencePositions(&InvalidParameterReferenceError{References: r.invalidReferences}, r.positions)
+	}
+
+	references := []string{}
+	for ref := range r.references {
+		references = append(references, ref)
+	}
+	sort.Strings(references)
+
+	if err := validateParameterReferences(references, referencePrefixesOf(r.service)); err != nil {
+		return withReferencePositions(err, r.positions)
+	}
+
+	resolved, err := resolveParameterReferences(ctx, r.service, references, r.optionalReferences, r.options)
+	if err != nil {
+		return withReferencePositions(err, r.positions)
+	}
+	r.resolved = resolved
+	return nil
+}
+
+//
+// Substitutes the placeholders of the next leaf collected, text must be that leaf
+func (r *documentResolver) resolveLeaf(text string, isKey bool) (resolvedLeaf, error) {
+	if isKey && !r.options.ResolveKeys {
+		return resolvedLeaf{text: text}, nil
+	}
+
+	tokens := r.leafTokens[r.nextLeaf]
+	r.nextLeaf++
+
+	if r.options.TypedValues && !isKey {
+		if token, found := wholePlaceholder(text, tokens); found && token.reason == "" && len(token.placeholder.filters) == 0 {
+			if param, ok := r.resolved[token.placeholder.reference]; ok {
+				return typedLeaf(param), nil
+			}
+			if token.placeholder.hasDefault {
+				return typedLeaf(SsmParameterInfo{Value: token.placeholder.defaultValue}), nil
+			}
+		}
+	}
+
+	resolvedText, err := r.scanner.replaceTokens(text, tokens, r.resolved, DocumentFormatNone)
+	return resolvedLeaf{text: resolvedText}, err
+}
+
+//
+// Returns the typed form of a parameter: a list for StringList parameters, otherwise a number or boolean
+// if the value reads as one, or the string itself
+func typedLeaf(param SsmParameterInfo) resolvedLeaf {
+	value := param.Value
+	switch {
+	case param.Type == stringListType && param.Elements != nil:
+		return resolvedLeaf{kind: listLeaf, text: value, list: param.Elements}
+	case param.Type == stringListType:
+		return resolvedLeaf{kind: listLeaf, text: value, list: splitStringList(value)}
+	case jsonNumber.MatchString(value):
+		return resolvedLeaf{kind: numberLeaf, text: value}
+	case value == "true" || value == "false":
+		return resolvedLeaf{kind: boolLeaf, text: value}
+	}
+	return resolvedLeaf{text: value}
+}
+
+//
+// JSON object that keeps the order of its members
+type orderedJSONObject []orderedJSONMember
+
+type orderedJSONMember struct {
+	Key   string
+	Value interface{}
+}
+
+func (o orderedJSONObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for i, member := range o {
+		if i > 0 {
+			buffer.WriteString(",")
+		}
+		key, err := jsonString(member.Key)
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteString(key + ":")
+
+		var value bytes.Buffer
+		encoder := json.NewEncoder(&value)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(member.Value); err != nil {
+			return nil, err
+		}
+		buffer.Write(bytes.TrimSuffix(value.Bytes(), []byte("\n")))
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+//
+// Decodes one JSON value, objects become orderedJSONObject and numbers json.Number
+func decodeOrderedJSON(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch delimiter := token.(type) {
+	case json.Delim:
+		if delimiter == '[' {
+			array := []interface{}{}
+			for decoder.More() {
+				element, err := decodeOrderedJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, element)
+			}
+			_, err := decoder.Token()
+			return array, err
+		}
+
+		object := orderedJSONObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, orderedJSONMember{Key: key.(string), Value: value})
+		}
+		_, err := decoder.Token()
+		return object, err
+	}
+	return token, nil
+}
+
+//
+// Calls visit for every string in a decoded JSON value and replaces it with the value visit returns.
+// Keys are passed with isKey set and can only be replaced by strings.
+func walkJSON(value interface{}, visit func(text string, isKey bool) (interface{}, error)) (interface{}, error) {
+	switch typed := value.(type) {
+	case string:
+		return visit(typed, false)
+
+	case []interface{}:
+		for i := range typed {
+			element, err := walkJSON(typed[i], visit)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = element
+		}
+
+	case orderedJSONObject:
+		keys := map[string]bool{}
+		for i := range typed {
+			key, err := visit(typed[i].Key, true)
+			if err != nil {
+				return nil, err
+			}
+			if key, ok := key.(string); ok {
+				typed[i].Key = key
+			}
+			if keys[typed[i].Key] {
+				return nil, errors.New("duplicate key " + strconv.Quote(typed[i].Key) + " after resolution")
+			}
+			keys[typed[i].Key] = true
+
+			member, err := walkJSON(typed[i].Value, visit)
+			if err != nil {
+				return nil, err
+			}
+			typed[i].Value = member
+		}
+	}
+	return value, nil
+}
+
+//
+// Calls visit for every string scalar of a YAML node tree, with isKey set for mapping keys.
+// Aliases are not followed, their anchors are visited where they are defined.
+func walkYAML(node *yaml.Node, isKey bool, visit func(node *yaml.Node, isKey bool) error) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() == "!!str" {
+			return visit(node, isKey)
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := walkYAML(node.Content[i], true, visit); err != nil {
+				return err
+			}
+			if err := walkYAML(node.Content[i+1], false, visit); err != nil {
+				return err
+			}
+		}
+
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := walkYAML(child, false, visit); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//
+// An unquoted placeholder like port: {{ssm:/app/port}} parses as a flow mapping with a flow mapping as its
+// only key. Such nodes are turned back into the string scalar of the placeholder, taken from input as
+// written: the parsed key loses a leading ? (the explicit key indicator) and can't be trusted.
+func restoreUnquotedPlaceholders(node *yaml.Node, input string, lineOffsets []int, scanner *placeholderScanner) {
+	isNull := func(node *yaml.Node) bool {
+		return node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" && node.Value == ""
+	}
+	isFlowPair := func(node *yaml.Node) bool {
+		return node.Kind == yaml.MappingNode && node.Style&yaml.FlowStyle != 0 && len(node.Content) == 2 && isNull(node.Content[1])
+	}
+
+	if isFlowPair(node) && isFlowPair(node.Content[0]) {
+		start := yamlNodeOffset(input, lineOffsets, node)
+		if strings.HasPrefix(input[start:], placeholderOpening) {
+			if token, found := scanner.tokenAt(input, start); found {
+				node.Kind = yaml.ScalarNode
+				node.Tag = "!!str"
+				node.Value = input[token.start:token.end]
+				node.Style = 0
+				node.Content = nil
+				return
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		restoreUnquotedPlaceholders(child, input, lineOffsets, scanner)
+	}
+}
+
+//
+// Returns the byte offsets at which the lines of input start
+func yamlLineOffsets(input string) []int {
+	offsets := []int{0}
+	for i := 0; i < len(input); i++ {
+		if input[i] == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+//
+// Returns the byte offset of node in input. yaml.v3 counts lines and columns from 1, columns in characters.
+func yamlNodeOffset(input string, lineOffsets []int, node *yaml.Node) int {
+	if node.Line < 1 || node.Line > len(lineOffsets) {
+		return len(input)
+	}
+	offset := lineOffsets[node.Line-1]
+	for column := 1; column < node.Column && offset < len(input); column++ {
+		_, size := utf8.DecodeRuneInString(input[offset:])
+		offset += size
+	}
+	return offset
+}
+
+//
+// Writes a resolved leaf into its scalar node. Typed leaves drop the quoting of the placeholder,
+// lists turn the node into a sequence.
+func setYAMLLeaf(node *yaml.Node, leaf resolvedLeaf) {
+	switch leaf.kind {
+	case stringLeaf:
+		node.Value = leaf.text
+		node.Tag = "!!str"
+
+	case numberLeaf:
+		node.Value = leaf.text
+		node.Tag = "!!float"
+		if _, err := strconv.ParseInt(leaf.text, 10, 64); err == nil {
+			node.Tag = "!!int"
+		}
+		node.Style = 0
+
+	case boolLeaf:
+		node.Value = leaf.text
+		node.Tag = "!!bool"
+		node.Style = 0
+
+	case listLeaf:
+		node.Kind = yaml.SequenceNode
+		node.Tag = "!!seq"
+		node.Value = ""
+		node.Style = 0
+		node.Content = nil
+		for _, element := range leaf.list {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: element})
+		}
+	}
+}
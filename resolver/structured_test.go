@@ -0,0 +1,165 @@
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStructuredServiceMock() ServiceMockedObjectWithRecords {
+	return NewServiceMockedObjectWithExtraRecords(map[string]SsmParameterInfo{
+		"ssm:/app/value": {Name: "/app/value", Type: stringType, Value: nastyValue},
+		"ssm:/app/port":  {Name: "/app/port", Type: stringType, Value: "5432"},
+		"ssm:/app/ratio": {Name: "/app/ratio", Type: stringType, Value: "0.25"},
+		"ssm:/app/debug": {Name: "/app/debug", Type: stringType, Value: "true"},
+		"ssm:/app/hosts": {Name: "/app/hosts", Type: stringListType, Value: "a.example.com,b.example.com"},
+		"ssm:/app/key":   {Name: "/app/key", Type: stringType, Value: "database"},
+	})
+}
+
+func TestResolveParametersInJSON(t *testing.T) {
+	serviceObject := newStructuredServiceMock()
+	input := `{"z": "{{ssm:/app/value}}", "{{ssm:/app/key}}": {"port": "{{ssm:/app/port}}", "hosts": "{{ssm:/app/hosts}}"},` +
+		` "a": ["{{ssm:/app/debug}}", "port {{ssm:/app/port}}", 1.50, null, "<{{ssm:/app/ratio}}>"]}`
+
+	output, err := ResolveParametersInJSON(&serviceObject, input, ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+  "z": "p@ss \"word\" it's\\ <b>&</b> ]]> -- ''' # x: y\nsecond line",
+  "{{ssm:/app/key}}": {
+    "port": "5432",
+    "hosts": "a.example.com,b.example.com"
+  },
+  "a": [
+    "true",
+    "port 5432",
+    1.50,
+    null,
+    "<0.25>"
+  ]
+}`, output)
+
+	output, err = ResolveParametersInJSON(&serviceObject, input, ResolveOptions{ResolveKeys: true, TypedValues: true})
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+  "z": "p@ss \"word\" it's\\ <b>&</b> ]]> -- ''' # x: y\nsecond line",
+  "database": {
+    "port": 5432,
+    "hosts": [
+      "a.example.com",
+      "b.example.com"
+    ]
+  },
+  "a": [
+    true,
+    "port 5432",
+    1.50,
+    null,
+    "<0.25>"
+  ]
+}`, output)
+}
+
+func TestResolveParametersInJSONTypedDefaults(t *testing.T) {
+	serviceObject := newStructuredServiceMock()
+
+	output, err := ResolveParametersInJSON(&serviceObject,
+		`{"timeout": "{{ssm:/app/timeout || \"30\"}}", "name": "{{ssm:/app/port | base64}}"}`,
+		ResolveOptions{TypedValues: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "{\n  \"timeout\": 30,\n  \"name\": \"NTQzMg==\"\n}", output)
+}
+
+func TestResolveParametersInJSONErrors(t *testing.T) {
+	serviceObject := newStructuredServiceMock()
+
+	_, err := ResolveParametersInJSON(&serviceObject, `{"a": "{{ssm:/app/missing}}", "b": "{{ssm:/app/port}}"}`, ResolveOptions{})
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+
+	_, err = ResolveParametersInJSON(&serviceObject, `{"a": "{{ssm:bad name}}"}`, ResolveOptions{})
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+
+	_, err = ResolveParametersInJSON(&serviceObject, `{"database": 1, "{{ssm:/app/key}}": 2}`, ResolveOptions{ResolveKeys: true})
+	assert.NotNil(t, err)
+
+	_, err = ResolveParametersInJSON(&serviceObject, `{"a": 1} {"b": 2}`, ResolveOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestResolveParametersInYAML(t *testing.T) {
+	serviceObject := newStructuredServiceMock()
+	input := `# application settings
+{{ssm:/app/key}}:
+  password: "{{ssm:/app/value}}" # quoted
+  port: '{{ssm:/app/port}}'
+  hosts: {{ssm:/app/hosts}}
+  debug: "{{ssm:/app/debug}}"
+literal: "{{ssm:/app/port}}"
+count: 3
+`
+
+	output, err := ResolveParametersInYAML(&serviceObject, input, ResolveOptions{ResolveKeys: true, TypedValues: true})
+	assert.Nil(t, err)
+	assert.Equal(t, `# application settings
+database:
+  password: "p@ss \"word\" it's\\ <b>&</b> ]]> -- ''' # x: y\nsecond line" # quoted
+  port: 5432
+  hosts:
+    - a.example.com
+    - b.example.com
+  debug: true
+literal: 5432
+count: 3
+`, output)
+
+	output, err = ResolveParametersInYAML(&serviceObject, input, ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, `# application settings
+'{{ssm:/app/key}}':
+  password: "p@ss \"word\" it's\\ <b>&</b> ]]> -- ''' # x: y\nsecond line" # quoted
+  port: '5432'
+  hosts: a.example.com,b.example.com
+  debug: "true"
+literal: "5432"
+count: 3
+`, output)
+}
+
+func TestResolveParametersInYAMLStream(t *testing.T) {
+	serviceObject := newStructuredServiceMock()
+
+	output, err := ResolveParametersInYAML(&serviceObject, "a: {{ssm:/app/port}}\n---\nb: x{{ssm:/app/debug}}\n", ResolveOptions{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "a: \"5432\"\n---\nb: xtrue\n", output)
+}
+
+func TestResolveParametersInYAMLUnquotedOptionalPlaceholders(t *testing.T) {
+	serviceObject := newStructuredServiceMock()
+	input := "é: {{?ssm:/app/missing}}\nport: {{ ssm:/app/port }}\n---\n" +
+		"name: {{ssm:/app/missing || \"fallback\"}}\nlist: [{{ ? ssm:/app/missing}}, {{ssm:/app/port}}]\n"
+
+	output, err := ResolveParametersInYAML(&serviceObject, input, ResolveOptions{TypedValues: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "é: \"\"\nport: 5432\n---\nname: fallback\nlist: [\"\", 5432]\n", output)
+}
+
+func TestStructuredResolutionErrorsCarryPositions(t *testing.T) {
+	serviceObject := newStructuredServiceMock()
+
+	_, err := ResolveParametersInJSON(&serviceObject, "{\n  \"a\": \"{{ssm:/app/port}}\",\n  \"b\": \"x {{ssm:/app/missing}}\"\n}", ResolveOptions{})
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, []PlaceholderPosition{{Offset: 40, Line: 3, Column: 11}}, resolutionError.Positions["ssm:/app/missing"])
+	assert.Contains(t, err.Error(), "ssm:/app/missing (at 3:11)")
+
+	_, err = ResolveParametersInYAML(&serviceObject, "a: 1\nb: {{ssm:bad name}}\n", ResolveOptions{})
+	var invalidReferenceError *InvalidParameterReferenceError
+	assert.True(t, errors.As(err, &invalidReferenceError))
+	assert.Equal(t, []PlaceholderPosition{{Offset: 8, Line: 2, Column: 4}}, invalidReferenceError.Positions["ssm:bad name"])
+}
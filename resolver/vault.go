@@ -0,0 +1,350 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//
+// Environment variables read by NewVaultProviderFromEnv, the same ones the vault CLI uses
+const VaultAddressEnvVariable = "VAULT_ADDR"
+const VaultTokenEnvVariable = "VAULT_TOKEN"
+const VaultNamespaceEnvVariable = "VAULT_NAMESPACE"
+
+const vaultNamespaceHeader = "X-Vault-Namespace"
+const vaultTokenHeader = "X-Vault-Token"
+const defaultVaultAppRoleMountPath = "approle"
+
+//
+// VaultProvider resolves references to secrets in a HashiCorp Vault KV version 2 secrets engine:
+//
+//	{{vault:secret/data/app#field}}             - one field of the latest version
+//	{{vault:secret/data/app?version=3#field}}   - one field of version 3
+//	{{vault:secret/data/app}}                   - all fields as a JSON object
+//
+// The path is the API path of the secret, including the data/ segment of the mount.
+// Vault secrets are secure, they are skipped when ResolveOptions.IgnoreSecureParameters is set.
+type VaultProvider struct {
+	// Vault server address, e.g. https://vault.example.com:8200
+	Address string
+	// Enterprise namespace, sent with every request if set
+	Namespace string
+	// Token used for reading secrets, obtained by AppRole login if empty and AppRole is set
+	Token   string
+	AppRole *VaultAppRoleAuth
+	// http.DefaultClient if not set
+	HTTPClient *http.Client
+
+	loginMutex sync.Mutex
+}
+
+//
+// VaultAppRoleAuth holds AppRole credentials. The token received from the login is
+// reused until Vault rejects it, then the provider logs in again.
+type VaultAppRoleAuth struct {
+	RoleID   string
+	SecretID string
+	// Mount path of the auth method, approle if not set
+	MountPath string
+}
+
+var _ IPrefixedParameterProvider = (*VaultProvider)(nil)
+
+func NewVaultProvider(address string, token string) *VaultProvider {
+	return &VaultProvider{Address: address, Token: token}
+}
+
+//
+// Creates a VaultProvider from the VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE environment variables
+func NewVaultProviderFromEnv() (*VaultProvider, error) {
+	address := os.Getenv(VaultAddressEnvVariable)
+	if address == "" {
+		return nil, errors.New(VaultAddressEnvVariable + " is not set")
+	}
+
+	provider := NewVaultProvider(address, os.Getenv(VaultTokenEnvVariable))
+	provider.Namespace = os.Getenv(VaultNamespaceEnvVariable)
+	return provider, nil
+}
+
+func (p *VaultProvider) ReferencePrefixes() []ReferencePrefix {
+	return []ReferencePrefix{{Prefix: vaultPrefix, Secure: true}}
+}
+
+//
+// Parsed form of a vault reference like vault:secret/data/app?version=3#field
+type vaultReference struct {
+	path    string
+	version int64
+	field   string
+}
+
+func parseVaultReference(reference string) (vaultReference, string) {
+	body := strings.TrimPrefix(reference, vaultPrefix)
+	result := vaultReference{}
+
+	if pos := strings.Index(body, "#"); pos >= 0 {
+		result.field = body[pos+1:]
+		body = body[:pos]
+		if result.field == "" {
+			return result, "field name after # is empty"
+		}
+	}
+
+	if pos := strings.Index(body, "?"); pos >= 0 {
+		query, err := url.ParseQuery(body[pos+1:])
+		body = body[:pos]
+		if err != nil || len(query) != 1 || len(query["version"]) != 1 {
+			return result, "only a version=N query is supported"
+		}
+		version, err := strconv.ParseInt(query.Get("version"), 10, 64)
+		if err != nil || version < 1 {
+			return result, "version must be a positive number"
+		}
+		result.version = version
+	}
+
+	result.path = body
+	if body == "" {
+		return result, "path is empty"
+	}
+	if strings.HasPrefix(body, "/") {
+		return result, "path must not begin with /"
+	}
+	for _, segment := range strings.Split(body, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return result, "path contains an empty, . or .. segment"
+		}
+	}
+
+	return result, ""
+}
+
+func (p *VaultProvider) GetParameters(ctx context.Context, parameterReferences []string) (map[string]SsmParameterInfo, error) {
+	type secretVersion struct {
+		path    string
+		version int64
+	}
+
+	versionOrder := []secretVersion{}
+	referencesByVersion := map[secretVersion][]string{}
+	parsedReferences := map[string]vaultReference{}
+	invalidReferences := []InvalidParameterReference{}
+
+	for _, ref := range parameterReferences {
+		parsed, reason := parseVaultReference(ref)
+		if reason != "" {
+			invalidReferences = append(invalidReferences, InvalidParameterReference{Reference: ref, Reason: reason})
+			continue
+		}
+
+		parsedReferences[ref] = parsed
+		version := secretVersion{path: parsed.path, version: parsed.version}
+		if _, found := referencesByVersion[version]; !found {
+			versionOrder = append(versionOrder, version)
+		}
+		referencesByVersion[version] = append(referencesByVersion[version], ref)
+	}
+
+	if len(invalidReferences) > 0 {
+		sort.Slice(invalidReferences, func(i, j int) bool { return invalidReferences[i].Reference < invalidReferences[j].Reference })
+		return nil, &InvalidParameterReferenceError{References: invalidReferences}
+	}
+
+	result := map[string]SsmParameterInfo{}
+	missingReferences := []string{}
+
+	// different fields of one secret version are served by a single request
+	for _, version := range versionOrder {
+		secret, err := p.readSecret(ctx, version.path, version.version)
+		if err != nil {
+			return nil, err
+		}
+		if secret == nil {
+			missingReferences = append(missingReferences, referencesByVersion[version]...)
+			continue
+		}
+
+		for _, ref := range referencesByVersion[version] {
+			field := parsedReferences[ref].field
+
+			value := ""
+			if field == "" {
+				data, err := json.Marshal(secret.Data)
+				if err != nil {
+					return nil, err
+				}
+				value = string(data)
+			} else if fieldValue, found := secret.Data[field]; found {
+				value = jsonValueAsText(fieldValue)
+			} else {
+				missingReferences = append(missingReferences, ref)
+				continue
+			}
+
+			result[ref] = SsmParameterInfo{
+				Name:    version.path,
+				Type:    vaultSecretType,
+				Value:   value,
+				Version: secret.Metadata.Version,
+			}
+		}
+	}
+
+	if len(missingReferences) > 0 {
+		return result, newMissingParametersError(missingReferences)
+	}
+	return result, nil
+}
+
+//
+// Data part of a KV v2 read response
+type vaultSecret struct {
+	Data     map[string]json.RawMessage `json:"data"`
+	Metadata struct {
+		Version int64 `json:"version"`
+	} `json:"metadata"`
+}
+
+//
+// Reads one version of a secret, 0 for the latest one. Returns nil if the secret or version
+// doesn't exist or is deleted.
+func (p *VaultProvider) readSecret(ctx context.Context, path string, version int64) (*vaultSecret, error) {
+	secretPath := "/v1/" + path
+	if version > 0 {
+		secretPath += "?version=" + strconv.FormatInt(version, 10)
+	}
+
+	token, err := p.token(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	response := struct {
+		Data *vaultSecret `json:"data"`
+	}{}
+	statusCode, err := p.send(ctx, http.MethodGet, secretPath, token, nil, &response)
+
+	// the AppRole token may have expired, log in again once
+	if statusCode == http.StatusForbidden && p.AppRole != nil {
+		if token, err = p.token(ctx, token); err != nil {
+			return nil, err
+		}
+		statusCode, err = p.send(ctx, http.MethodGet, secretPath, token, nil, &response)
+	}
+
+	if statusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response.Data == nil || response.Data.Data == nil {
+		return nil, nil
+	}
+	return response.Data, nil
+}
+
+//
+// Returns the token to read secrets with. A token equal to rejectedToken is replaced by a new AppRole login.
+func (p *VaultProvider) token(ctx context.Context, rejectedToken string) (string, error) {
+	p.loginMutex.Lock()
+	defer p.loginMutex.Unlock()
+
+	if p.Token != "" && p.Token != rejectedToken {
+		return p.Token, nil
+	}
+	if p.AppRole == nil {
+		if p.Token == "" {
+			return "", errors.New("vault token is not set and AppRole authentication is not configured")
+		}
+		return p.Token, nil
+	}
+
+	mountPath := p.AppRole.MountPath
+	if mountPath == "" {
+		mountPath = defaultVaultAppRoleMountPath
+	}
+
+	request := map[string]string{"role_id": p.AppRole.RoleID, "secret_id": p.AppRole.SecretID}
+	response := struct {
+		Auth *struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	if _, err := p.send(ctx, http.MethodPost, "/v1/auth/"+strings.Trim(mountPath, "/")+"/login", "", request, &response); err != nil {
+		return "", err
+	}
+	if response.Auth == nil || response.Auth.ClientToken == "" {
+		return "", errors.New("vault AppRole login returned no client token")
+	}
+
+	p.Token = response.Auth.ClientToken
+	return p.Token, nil
+}
+
+//
+// Sends a request to the Vault API and decodes the JSON response into output. Returns the HTTP status code,
+// and an error with the messages reported by Vault for any status other than 200.
+func (p *VaultProvider) send(ctx context.Context, method string, path string, token string, input interface{}, output interface{}) (int, error) {
+	var body io.Reader
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(p.Address, "/")+path, body)
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		request.Header.Set(vaultTokenHeader, token)
+	}
+	if p.Namespace != "" {
+		request.Header.Set(vaultNamespaceHeader, p.Namespace)
+	}
+	if input != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		vaultErrors := struct {
+			Errors []string `json:"errors"`
+		}{}
+		_ = json.Unmarshal(data, &vaultErrors)
+		return response.StatusCode, fmt.Errorf("vault %s %s failed with status %d: %s",
+			method, path, response.StatusCode, strings.Join(vaultErrors.Errors, "; "))
+	}
+
+	return response.StatusCode, json.Unmarshal(data, output)
+}
@@ -0,0 +1,231 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Fake Vault server with a KV v2 engine mounted at secret/ and an AppRole auth method at approle/
+type vaultServerMock struct {
+	mutex sync.Mutex
+	// secret path to its versions, version 1 first
+	secrets      map[string][]map[string]interface{}
+	validTokens  map[string]bool
+	namespace    string
+	logins       int
+	secretReads  []string
+	appRoleToken string
+}
+
+func newVaultServerMock() *vaultServerMock {
+	return &vaultServerMock{
+		secrets: map[string][]map[string]interface{}{
+			"secret/data/app": {
+				{"password": "old"},
+				{"password": "p@ss", "port": 5432, "tls": true},
+			},
+		},
+		validTokens: map[string]bool{"root-token": true},
+	}
+}
+
+func (m *vaultServerMock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	writeJSON := func(status int, body interface{}) {
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+
+	if r.Header.Get(vaultNamespaceHeader) != m.namespace {
+		writeJSON(http.StatusForbidden, map[string]interface{}{"errors": []string{"wrong namespace"}})
+		return
+	}
+
+	if r.URL.Path == "/v1/auth/approle/login" {
+		credentials := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&credentials)
+		if credentials["role_id"] != "role" || credentials["secret_id"] != "secret" {
+			writeJSON(http.StatusBadRequest, map[string]interface{}{"errors": []string{"invalid role or secret ID"}})
+			return
+		}
+		m.logins++
+		m.appRoleToken = "approle-token-" + string(rune('0'+m.logins))
+		m.validTokens[m.appRoleToken] = true
+		writeJSON(http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{"client_token": m.appRoleToken}})
+		return
+	}
+
+	if !m.validTokens[r.Header.Get(vaultTokenHeader)] {
+		writeJSON(http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	m.secretReads = append(m.secretReads, r.URL.RequestURI())
+
+	versions, found := m.secrets[path]
+	version := len(versions)
+	if requestedVersion := r.URL.Query().Get("version"); requestedVersion != "" {
+		version = int(requestedVersion[0] - '0')
+	}
+	if !found || version > len(versions) {
+		writeJSON(http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+
+	writeJSON(http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+		"data":     versions[version-1],
+		"metadata": map[string]interface{}{"version": version},
+	}})
+}
+
+func TestParseVaultReference(t *testing.T) {
+	testCases := map[string]vaultReference{
+		"vault:secret/data/app":                    {path: "secret/data/app"},
+		"vault:secret/data/app#password":           {path: "secret/data/app", field: "password"},
+		"vault:secret/data/app?version=3#password": {path: "secret/data/app", version: 3, field: "password"},
+		"vault:secret/data/app?version=2":          {path: "secret/data/app", version: 2},
+	}
+	for reference, expected := range testCases {
+		parsed, reason := parseVaultReference(reference)
+		assert.Equal(t, "", reason, reference)
+		assert.Equal(t, expected, parsed, reference)
+	}
+
+	for _, reference := range []string{
+		"vault:",
+		"vault:/secret/data/app",
+		"vault:secret/data/../app",
+		"vault:secret//app",
+		"vault:secret/data/app#",
+		"vault:secret/data/app?version=0",
+		"vault:secret/data/app?version=latest",
+		"vault:secret/data/app?ttl=5",
+	} {
+		_, reason := parseVaultReference(reference)
+		assert.NotEqual(t, "", reason, reference)
+	}
+}
+
+func TestVaultProviderGetParametersWithToken(t *testing.T) {
+	vault := newVaultServerMock()
+	vault.namespace = "team-a"
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	provider := NewVaultProvider(server.URL, "root-token")
+	provider.Namespace = "team-a"
+
+	result, err := provider.GetParameters(context.Background(), []string{
+		"vault:secret/data/app#password",
+		"vault:secret/data/app#port",
+		"vault:secret/data/app?version=1#password",
+		"vault:secret/data/app#missing",
+		"vault:secret/data/other#password",
+	})
+
+	var missingParametersError *MissingParametersError
+	assert.True(t, errors.As(err, &missingParametersError))
+	assert.Equal(t, []string{"vault:secret/data/app#missing", "vault:secret/data/other#password"}, missingParametersError.References)
+
+	assert.Equal(t, SsmParameterInfo{Name: "secret/data/app", Type: vaultSecretType, Value: "p@ss", Version: 2}, result["vault:secret/data/app#password"])
+	assert.Equal(t, "5432", result["vault:secret/data/app#port"].Value)
+	assert.Equal(t, "old", result["vault:secret/data/app?version=1#password"].Value)
+	assert.Equal(t, int64(1), result["vault:secret/data/app?version=1#password"].Version)
+
+	// fields of one secret version are read once
+	assert.Equal(t, []string{"/v1/secret/data/app", "/v1/secret/data/app?version=1", "/v1/secret/data/other"}, vault.secretReads)
+}
+
+func TestVaultProviderReturnsWholeSecretAsJson(t *testing.T) {
+	server := httptest.NewServer(newVaultServerMock())
+	defer server.Close()
+
+	result, err := NewVaultProvider(server.URL, "root-token").GetParameters(context.Background(), []string{"vault:secret/data/app"})
+
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"password":"p@ss","port":5432,"tls":true}`, result["vault:secret/data/app"].Value)
+}
+
+func TestVaultProviderAppRoleLogin(t *testing.T) {
+	vault := newVaultServerMock()
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	provider := NewVaultProvider(server.URL, "")
+	provider.AppRole = &VaultAppRoleAuth{RoleID: "role", SecretID: "secret"}
+
+	result, err := provider.GetParameters(context.Background(), []string{"vault:secret/data/app#password"})
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", result["vault:secret/data/app#password"].Value)
+	assert.Equal(t, 1, vault.logins)
+
+	// the token is reused until it is rejected, then the provider logs in again
+	_, err = provider.GetParameters(context.Background(), []string{"vault:secret/data/app#password"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, vault.logins)
+
+	delete(vault.validTokens, vault.appRoleToken)
+	result, err = provider.GetParameters(context.Background(), []string{"vault:secret/data/app#password"})
+	assert.Nil(t, err)
+	assert.Equal(t, "p@ss", result["vault:secret/data/app#password"].Value)
+	assert.Equal(t, 2, vault.logins)
+
+	provider = NewVaultProvider(server.URL, "")
+	provider.AppRole = &VaultAppRoleAuth{RoleID: "role", SecretID: "wrong"}
+	_, err = provider.GetParameters(context.Background(), []string{"vault:secret/data/app#password"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid role or secret ID")
+}
+
+func TestVaultProviderReportsPermissionErrors(t *testing.T) {
+	server := httptest.NewServer(newVaultServerMock())
+	defer server.Close()
+
+	_, err := NewVaultProvider(server.URL, "revoked-token").GetParameters(context.Background(), []string{"vault:secret/data/app#password"})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestResolveParametersInTextWithVaultProvider(t *testing.T) {
+	server := httptest.NewServer(newVaultServerMock())
+	defer server.Close()
+
+	router := NewProviderRouter().
+		Register(NewEnvironmentProvider()).
+		Register(NewVaultProvider(server.URL, "root-token"))
+	t.Setenv("RESOLVER_TEST_USER", "admin")
+
+	output, err := ResolveParametersInText(router, "{{env:RESOLVER_TEST_USER}}:{{vault:secret/data/app#password}}", ResolveOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "admin:p@ss", output)
+
+	output, err = ResolveParametersInText(router, "{{vault:secret/data/app#password}}", ResolveOptions{IgnoreSecureParameters: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "{{vault:secret/data/app#password}}", output)
+}
+
+func TestVaultReferencesAreTypeChecked(t *testing.T) {
+	// a provider registered for vault: that doesn't return vault secrets
+	provider := ParameterProviderFunc(func(ctx context.Context, refs []string) (map[string]SsmParameterInfo, error) {
+		return map[string]SsmParameterInfo{refs[0]: {Name: "app", Type: stringType, Value: "value"}}, nil
+	})
+	router := NewProviderRouter().Register(provider, ReferencePrefix{Prefix: vaultPrefix, Secure: true})
+
+	_, err := ResolveParametersInText(router, "{{vault:secret/data/app#password}}", ResolveOptions{})
+
+	var resolutionError *ParameterResolutionError
+	assert.True(t, errors.As(err, &resolutionError))
+	assert.Equal(t, []ParameterTypeMismatch{{Reference: "vault:secret/data/app#password", Prefix: vaultPrefix, Type: stringType}}, resolutionError.TypeMismatches)
+}